@@ -0,0 +1,331 @@
+package gonc
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+)
+
+// CDF-5 (NC_64BIT_DATA) widens every "non-negative" header field from the
+// classic format's 4 bytes to 8: numrecs, the dim/attr/var list counts,
+// name lengths, dimids, and vsize. begin (the variable's file offset) is
+// likewise always 8 bytes, where CDF-1 used 4 and CDF-2 used 8. nc_type
+// stays a 4-byte enum in every format.
+
+func readU64(r io.Reader) (uint64, error) {
+	buf := make([]byte, 8)
+	if _, err := io.ReadFull(r, buf); err != nil {
+		return 0, err
+	}
+	return binary.BigEndian.Uint64(buf), nil
+}
+
+func readString64(r *io.SectionReader) (string, error) {
+	n, err := readU64(r)
+	if err != nil {
+		return "", err
+	}
+
+	rem, err := remaining(r)
+	if err != nil {
+		return "", err
+	}
+	if int64(n) > rem {
+		return "", fmt.Errorf("gonc: string length %d exceeds %d remaining header bytes", n, rem)
+	}
+
+	buf := make([]byte, n)
+	if _, err := io.ReadFull(r, buf); err != nil {
+		return "", err
+	}
+
+	pad := (4 - (n % 4)) % 4
+	if pad > 0 {
+		if _, err := io.ReadFull(r, make([]byte, pad)); err != nil {
+			return "", err
+		}
+	}
+
+	return string(buf), nil
+}
+
+func openCDF5(r io.ReaderAt, size int64) (*File, error) {
+	hr := io.NewSectionReader(r, 0, size)
+	if _, err := hr.Seek(4, io.SeekStart); err != nil {
+		return nil, err
+	}
+
+	numrecs, err := readU64(hr)
+	if err != nil {
+		return nil, err
+	}
+
+	nc := &File{
+		r:       r,
+		size:    size,
+		Format:  CDF5Format,
+		NumRecs: uint32(numrecs),
+		Dims:    []Dimension{},
+		Vars:    []Variable{},
+	}
+
+	if err := nc.readDimList64(hr); err != nil {
+		return nil, err
+	}
+
+	attrs, err := nc.readAttrList64(hr)
+	if err != nil {
+		return nil, err
+	}
+	nc.Attrs = attrs
+
+	if err := nc.readVarList64(hr); err != nil {
+		return nil, err
+	}
+
+	return nc, nil
+}
+
+// minDimSize64 is the fewest bytes a single CDF-5 dim_list entry can
+// occupy: a zero-length name (8 bytes) plus its 8-byte length field.
+const minDimSize64 = 16
+
+func (nc *File) readDimList64(r *io.SectionReader) error {
+	tag, err := readU32(r)
+	if err != nil {
+		return err
+	}
+
+	if tag == 0 {
+		nc.Dims = []Dimension{}
+		return nil
+	}
+
+	if tag != 0x0A {
+		return fmt.Errorf("invalid dim_list tag: %d", tag)
+	}
+
+	nelems, err := readU64(r)
+	if err != nil {
+		return err
+	}
+
+	rem, err := remaining(r)
+	if err != nil {
+		return err
+	}
+	if int64(nelems) > rem/minDimSize64 {
+		return fmt.Errorf("gonc: dim_list claims %d entries, but only %d header bytes remain", nelems, rem)
+	}
+
+	dims := make([]Dimension, 0, nelems)
+
+	for i := uint64(0); i < nelems; i++ {
+		name, err := readString64(r)
+		if err != nil {
+			return err
+		}
+
+		length, err := readU64(r)
+		if err != nil {
+			return err
+		}
+
+		dims = append(dims, Dimension{Name: name, Length: uint32(length), Length64: length})
+	}
+
+	nc.Dims = dims
+	return nil
+}
+
+// minAttrSize64 is the fewest bytes a single CDF-5 attr_list entry can
+// occupy: a zero-length name (8), a 4-byte type, and an 8-byte value
+// count.
+const minAttrSize64 = 20
+
+func (nc *File) readAttrList64(r *io.SectionReader) ([]Attribute, error) {
+	tag, err := readU32(r)
+	if err != nil {
+		return nil, err
+	}
+
+	if tag == 0 {
+		return []Attribute{}, nil
+	}
+
+	if tag != 0x0C {
+		return nil, fmt.Errorf("invalid attr_list tag: %d", tag)
+	}
+
+	nelems, err := readU64(r)
+	if err != nil {
+		return nil, err
+	}
+
+	rem, err := remaining(r)
+	if err != nil {
+		return nil, err
+	}
+	if int64(nelems) > rem/minAttrSize64 {
+		return nil, fmt.Errorf("gonc: attr_list claims %d entries, but only %d header bytes remain", nelems, rem)
+	}
+
+	attrs := make([]Attribute, 0, nelems)
+
+	for i := uint64(0); i < nelems; i++ {
+		name, err := readString64(r)
+		if err != nil {
+			return nil, err
+		}
+
+		atype, err := readU32(r)
+		if err != nil {
+			return nil, err
+		}
+
+		nvals, err := readU64(r)
+		if err != nil {
+			return nil, err
+		}
+
+		rem, err := remaining(r)
+		if err != nil {
+			return nil, err
+		}
+		if int64(nvals) > rem {
+			return nil, fmt.Errorf("gonc: attribute %q values length %d exceeds %d remaining header bytes", name, nvals, rem)
+		}
+
+		buf := make([]byte, nvals)
+		if _, err := io.ReadFull(r, buf); err != nil {
+			return nil, err
+		}
+
+		pad := (4 - (nvals % 4)) % 4
+		if pad > 0 {
+			if _, err := io.ReadFull(r, make([]byte, pad)); err != nil {
+				return nil, err
+			}
+		}
+
+		attrs = append(attrs, Attribute{
+			Name:   name,
+			Type:   atype,
+			Values: buf,
+		})
+	}
+
+	return attrs, nil
+}
+
+// minVarSize64 is the fewest bytes a single CDF-5 var_list entry can
+// occupy: a zero-length name (8), a zero dim count (8), an empty
+// attr_list tag (4), a 4-byte type, an 8-byte vsize and an 8-byte offset.
+const minVarSize64 = 40
+
+func (nc *File) readVarList64(r *io.SectionReader) error {
+	tag, err := readU32(r)
+	if err != nil {
+		return err
+	}
+
+	if tag == 0 {
+		nc.Vars = []Variable{}
+		return nil
+	}
+
+	if tag != 0x0B {
+		return fmt.Errorf("invalid var_list tag: %d", tag)
+	}
+
+	nelems, err := readU64(r)
+	if err != nil {
+		return err
+	}
+
+	rem, err := remaining(r)
+	if err != nil {
+		return err
+	}
+	if int64(nelems) > rem/minVarSize64 {
+		return fmt.Errorf("gonc: var_list claims %d entries, but only %d header bytes remain", nelems, rem)
+	}
+
+	vars := make([]Variable, 0, nelems)
+
+	for i := uint64(0); i < nelems; i++ {
+		name, err := readString64(r)
+		if err != nil {
+			return err
+		}
+
+		nDims, err := readU64(r)
+		if err != nil {
+			return err
+		}
+
+		remDims, err := remaining(r)
+		if err != nil {
+			return err
+		}
+		if int64(nDims) > remDims/8 {
+			return fmt.Errorf("gonc: variable %q claims %d dimensions, but only %d header bytes remain", name, nDims, remDims)
+		}
+
+		dimIDs := make([]uint32, nDims)
+		for j := uint64(0); j < nDims; j++ {
+			id, err := readU64(r)
+			if err != nil {
+				return err
+			}
+			if id >= uint64(len(nc.Dims)) {
+				return fmt.Errorf("gonc: variable %q references dim id %d, but the file only has %d dims", name, id, len(nc.Dims))
+			}
+			dimIDs[j] = uint32(id)
+		}
+
+		attrs, err := nc.readAttrList64(r)
+		if err != nil {
+			return err
+		}
+
+		dtype, err := readU32(r)
+		if err != nil {
+			return err
+		}
+
+		vsize, err := readU64(r)
+		if err != nil {
+			return err
+		}
+
+		offset, err := readU64(r)
+		if err != nil {
+			return err
+		}
+
+		isRecord := len(dimIDs) > 0 && nc.Dims[dimIDs[0]].Length == 0
+		if !isRecord && int64(offset)+int64(vsize) > nc.size {
+			return fmt.Errorf("gonc: variable %q data [%d, %d) extends past the %d-byte file", name, offset, offset+vsize, nc.size)
+		}
+
+		v := Variable{
+			Name:     name,
+			DimIDs:   dimIDs,
+			Attrs:    attrs,
+			DataType: dtype,
+			VSize:    uint32(vsize),
+			Offset:   uint32(offset),
+			VSize64:  vsize,
+			Offset64: offset,
+		}
+		vars = append(vars, v)
+	}
+
+	for i := range vars {
+		vars[i].nc = nc
+	}
+
+	nc.Vars = vars
+	return nil
+}
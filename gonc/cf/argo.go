@@ -0,0 +1,228 @@
+package cf
+
+import (
+	"fmt"
+	"time"
+
+	"floatchat-gopy/gonc"
+)
+
+// ArgoProfile is a CF/Argo-convention view over a single-profile netCDF
+// file: the handful of variables every Argo profile carries, decoded to
+// physical units with fill values mapped to NaN and QC flags alongside
+// each measured field.
+type ArgoProfile struct {
+	Latitude  float64
+	Longitude float64
+	JULD      time.Time
+
+	Pres []float64
+	Temp []float64
+	Psal []float64
+
+	PresQC []byte
+	TempQC []byte
+	PsalQC []byte
+
+	nc *gonc.File
+}
+
+// OpenProfile opens path and decodes it as a single Argo profile using CF
+// conventions: _FillValue/scale_factor/add_offset unpacking for PRES/
+// TEMP/PSAL, a UDUNITS "<unit> since <reference>" JULD, and the
+// corresponding *_QC companion variables.
+func OpenProfile(path string) (*ArgoProfile, error) {
+	nc, err := gonc.Open(path)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &ArgoProfile{nc: nc}
+
+	if p.Latitude, err = scalarFloat(nc, "LATITUDE"); err != nil {
+		nc.Close()
+		return nil, err
+	}
+	if p.Longitude, err = scalarFloat(nc, "LONGITUDE"); err != nil {
+		nc.Close()
+		return nil, err
+	}
+
+	juldVar, err := findVar(nc, "JULD")
+	if err != nil {
+		nc.Close()
+		return nil, err
+	}
+	juldRaw, err := scalarFloat(nc, "JULD")
+	if err != nil {
+		nc.Close()
+		return nil, err
+	}
+	toTime, err := ParseUDUnitsTime(Units(juldVar))
+	if err != nil {
+		nc.Close()
+		return nil, err
+	}
+	p.JULD = toTime(juldRaw)
+
+	fields := []struct {
+		name   string
+		qcName string
+		dst    *[]float64
+		qc     *[]byte
+	}{
+		{"PRES", "PRES_QC", &p.Pres, &p.PresQC},
+		{"TEMP", "TEMP_QC", &p.Temp, &p.TempQC},
+		{"PSAL", "PSAL_QC", &p.Psal, &p.PsalQC},
+	}
+	for _, f := range fields {
+		if _, err := findVar(nc, f.name); err != nil {
+			// Not every Argo profile carries every measured field.
+			continue
+		}
+		vals, err := profileFloats(nc, f.name)
+		if err != nil {
+			nc.Close()
+			return nil, err
+		}
+		*f.dst = vals
+
+		if qcVar, err := findVar(nc, f.qcName); err == nil {
+			qc, err := readQC(nc, qcVar)
+			if err != nil {
+				nc.Close()
+				return nil, err
+			}
+			*f.qc = qc
+		}
+	}
+
+	return p, nil
+}
+
+// Close releases the underlying netCDF file.
+func (p *ArgoProfile) Close() error {
+	return p.nc.Close()
+}
+
+func findVar(nc *gonc.File, name string) (*gonc.Variable, error) {
+	for i := range nc.Vars {
+		if nc.Vars[i].Name == name {
+			return &nc.Vars[i], nil
+		}
+	}
+	return nil, fmt.Errorf("cf: variable %q not found", name)
+}
+
+// fullHyperslab builds the start/count pair that reads the whole of v,
+// resolving the unlimited dimension (length 0 in the header) against the
+// file's actual record count.
+func fullHyperslab(nc *gonc.File, v *gonc.Variable) (start, count []int) {
+	ndims := len(v.DimIDs)
+	start = make([]int, ndims)
+	count = make([]int, ndims)
+	for i, id := range v.DimIDs {
+		length := nc.Dims[id].Length
+		if length == 0 {
+			length = nc.NumRecs
+		}
+		count[i] = int(length)
+	}
+	return start, count
+}
+
+// scalarFloat reads the whole of a variable expected to hold exactly one
+// value (LATITUDE, LONGITUDE, JULD in a single-profile file) and unpacks
+// it to physical units.
+func scalarFloat(nc *gonc.File, name string) (float64, error) {
+	vals, err := profileFloats(nc, name)
+	if err != nil {
+		return 0, err
+	}
+	if len(vals) == 0 {
+		return 0, fmt.Errorf("cf: variable %q has no data", name)
+	}
+	return vals[0], nil
+}
+
+// profileFloats reads the whole of a variable and unpacks it to physical
+// units, mapping _FillValue to NaN.
+func profileFloats(nc *gonc.File, name string) ([]float64, error) {
+	v, err := findVar(nc, name)
+	if err != nil {
+		return nil, err
+	}
+
+	start, count := fullHyperslab(nc, v)
+	data, err := v.Read(start, count, nil)
+	if err != nil {
+		return nil, err
+	}
+	raw, err := toFloat64(data)
+	if err != nil {
+		return nil, err
+	}
+
+	var fv *float64
+	if f, ok := FillValue(v); ok {
+		fv = &f
+	}
+	scale, offset := ScaleOffset(v)
+	return ApplyScaling(raw, fv, scale, offset), nil
+}
+
+// readQC reads the whole of a *_QC companion variable, which Argo stores
+// as single-character NC_CHAR codes (one per measurement).
+func readQC(nc *gonc.File, v *gonc.Variable) ([]byte, error) {
+	start, count := fullHyperslab(nc, v)
+	data, err := v.Read(start, count, nil)
+	if err != nil {
+		return nil, err
+	}
+	b, ok := data.([]byte)
+	if !ok {
+		return nil, fmt.Errorf("cf: variable %q is not NC_CHAR", v.Name)
+	}
+	return b, nil
+}
+
+// toFloat64 widens any of Variable.Read's possible return types to
+// []float64 so callers don't need a type switch of their own.
+func toFloat64(data interface{}) ([]float64, error) {
+	switch vv := data.(type) {
+	case []float64:
+		return vv, nil
+	case []float32:
+		out := make([]float64, len(vv))
+		for i, x := range vv {
+			out[i] = float64(x)
+		}
+		return out, nil
+	case []int32:
+		out := make([]float64, len(vv))
+		for i, x := range vv {
+			out[i] = float64(x)
+		}
+		return out, nil
+	case []int16:
+		out := make([]float64, len(vv))
+		for i, x := range vv {
+			out[i] = float64(x)
+		}
+		return out, nil
+	case []int8:
+		out := make([]float64, len(vv))
+		for i, x := range vv {
+			out[i] = float64(x)
+		}
+		return out, nil
+	case []byte:
+		out := make([]float64, len(vv))
+		for i, x := range vv {
+			out[i] = float64(x)
+		}
+		return out, nil
+	default:
+		return nil, fmt.Errorf("cf: unsupported variable data type %T", data)
+	}
+}
@@ -0,0 +1,135 @@
+package cf
+
+import (
+	"math"
+	"os"
+	"testing"
+	"time"
+
+	"floatchat-gopy/gonc"
+)
+
+// writeArgoFixture builds a minimal single-profile netCDF file exercising
+// the conventions OpenProfile relies on: a JULD units attribute, and
+// _FillValue/scale_factor/add_offset packing on PRES.
+func writeArgoFixture(t *testing.T) string {
+	t.Helper()
+
+	path := t.TempDir() + "/profile.nc"
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	wr, err := gonc.NewWriter(f, gonc.ClassicFormat)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	profID, err := wr.DefineDim("N_PROF", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	levelID, err := wr.DefineDim("N_LEVELS", 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	latID, err := wr.DefineVar("LATITUDE", gonc.NC_DOUBLE, []gonc.DimID{profID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	lonID, err := wr.DefineVar("LONGITUDE", gonc.NC_DOUBLE, []gonc.DimID{profID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	juldID, err := wr.DefineVar("JULD", gonc.NC_DOUBLE, []gonc.DimID{profID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := wr.PutAttr(juldID, "units", "days since 1950-01-01 00:00:00 UTC"); err != nil {
+		t.Fatal(err)
+	}
+
+	presID, err := wr.DefineVar("PRES", gonc.NC_FLOAT, []gonc.DimID{profID, levelID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := wr.PutAttr(presID, "_FillValue", []float32{-999}); err != nil {
+		t.Fatal(err)
+	}
+	if err := wr.PutAttr(presID, "scale_factor", []float64{2}); err != nil {
+		t.Fatal(err)
+	}
+	if err := wr.PutAttr(presID, "add_offset", []float64{1}); err != nil {
+		t.Fatal(err)
+	}
+
+	presQCID, err := wr.DefineVar("PRES_QC", gonc.NC_CHAR, []gonc.DimID{profID, levelID})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := wr.EndDef(); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := wr.WriteVar(latID, []int{0}, []int{1}, []float64{45.5}); err != nil {
+		t.Fatal(err)
+	}
+	if err := wr.WriteVar(lonID, []int{0}, []int{1}, []float64{-12.25}); err != nil {
+		t.Fatal(err)
+	}
+	if err := wr.WriteVar(juldID, []int{0}, []int{1}, []float64{25202.5}); err != nil {
+		t.Fatal(err)
+	}
+	if err := wr.WriteVar(presID, []int{0, 0}, []int{1, 3}, []float32{5, -999, 15}); err != nil {
+		t.Fatal(err)
+	}
+	if err := wr.WriteVar(presQCID, []int{0, 0}, []int{1, 3}, []byte("1\x009")); err != nil {
+		t.Fatal(err)
+	}
+
+	return path
+}
+
+func TestOpenProfile(t *testing.T) {
+	path := writeArgoFixture(t)
+
+	p, err := OpenProfile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.Close()
+
+	if p.Latitude != 45.5 {
+		t.Errorf("Latitude = %v, want 45.5", p.Latitude)
+	}
+	if p.Longitude != -12.25 {
+		t.Errorf("Longitude = %v, want -12.25", p.Longitude)
+	}
+
+	wantJULD := time.Date(1950, 1, 1, 0, 0, 0, 0, time.UTC).Add(25202*24*time.Hour + 12*time.Hour)
+	if !p.JULD.Equal(wantJULD) {
+		t.Errorf("JULD = %v, want %v", p.JULD, wantJULD)
+	}
+
+	if len(p.Pres) != 3 {
+		t.Fatalf("len(Pres) = %d, want 3", len(p.Pres))
+	}
+	// raw 5 -> 5*2+1 = 11, raw -999 is the fill value -> NaN, raw 15 -> 31.
+	if p.Pres[0] != 11 {
+		t.Errorf("Pres[0] = %v, want 11", p.Pres[0])
+	}
+	if !math.IsNaN(p.Pres[1]) {
+		t.Errorf("Pres[1] = %v, want NaN", p.Pres[1])
+	}
+	if p.Pres[2] != 31 {
+		t.Errorf("Pres[2] = %v, want 31", p.Pres[2])
+	}
+
+	if string(p.PresQC) != "1\x009" {
+		t.Errorf("PresQC = %q, want %q", p.PresQC, "1\x009")
+	}
+}
@@ -0,0 +1,205 @@
+// Package cf interprets CF-1.x / Argo netCDF conventions on top of the
+// raw gonc.Variable/gonc.Attribute types: fill values, scale_factor/
+// add_offset unpacking, UDUNITS-style time units, standard names, and
+// coordinate variables. It does not parse a new file format of its own —
+// everything here is a convenience view over an already-open gonc.File.
+package cf
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"floatchat-gopy/gonc"
+)
+
+// FindAttr returns the named attribute out of attrs, if present.
+func FindAttr(attrs []gonc.Attribute, name string) (gonc.Attribute, bool) {
+	for _, a := range attrs {
+		if a.Name == name {
+			return a, true
+		}
+	}
+	return gonc.Attribute{}, false
+}
+
+// AttrFloat64 decodes a numeric attribute's first value as a float64,
+// regardless of its underlying NetCDF type.
+func AttrFloat64(a gonc.Attribute) (float64, error) {
+	switch a.Type {
+	case gonc.NC_BYTE:
+		if len(a.Values) < 1 {
+			return 0, fmt.Errorf("cf: empty %s attribute", a.Name)
+		}
+		return float64(int8(a.Values[0])), nil
+	case gonc.NC_SHORT:
+		if len(a.Values) < 2 {
+			return 0, fmt.Errorf("cf: truncated %s attribute", a.Name)
+		}
+		return float64(int16(binary.BigEndian.Uint16(a.Values))), nil
+	case gonc.NC_INT:
+		if len(a.Values) < 4 {
+			return 0, fmt.Errorf("cf: truncated %s attribute", a.Name)
+		}
+		return float64(int32(binary.BigEndian.Uint32(a.Values))), nil
+	case gonc.NC_FLOAT:
+		if len(a.Values) < 4 {
+			return 0, fmt.Errorf("cf: truncated %s attribute", a.Name)
+		}
+		return float64(math.Float32frombits(binary.BigEndian.Uint32(a.Values))), nil
+	case gonc.NC_DOUBLE:
+		if len(a.Values) < 8 {
+			return 0, fmt.Errorf("cf: truncated %s attribute", a.Name)
+		}
+		return math.Float64frombits(binary.BigEndian.Uint64(a.Values)), nil
+	case gonc.NC_CHAR:
+		v, err := strconv.ParseFloat(strings.TrimRight(string(a.Values), "\x00"), 64)
+		if err != nil {
+			return 0, fmt.Errorf("cf: %s attribute is not numeric: %w", a.Name, err)
+		}
+		return v, nil
+	default:
+		return 0, fmt.Errorf("cf: unsupported attribute type %d", a.Type)
+	}
+}
+
+// AttrString decodes a character attribute's value as a string.
+func AttrString(a gonc.Attribute) string {
+	return strings.TrimRight(string(a.Values), "\x00")
+}
+
+// FillValue returns a variable's _FillValue attribute, if present.
+func FillValue(v *gonc.Variable) (float64, bool) {
+	a, ok := FindAttr(v.Attrs, "_FillValue")
+	if !ok {
+		return 0, false
+	}
+	f, err := AttrFloat64(a)
+	if err != nil {
+		return 0, false
+	}
+	return f, true
+}
+
+// ScaleOffset returns a variable's scale_factor and add_offset
+// attributes, defaulting to 1 and 0 respectively when absent, per the CF
+// packed-data conventions.
+func ScaleOffset(v *gonc.Variable) (scale, offset float64) {
+	scale, offset = 1, 0
+	if a, ok := FindAttr(v.Attrs, "scale_factor"); ok {
+		if f, err := AttrFloat64(a); err == nil {
+			scale = f
+		}
+	}
+	if a, ok := FindAttr(v.Attrs, "add_offset"); ok {
+		if f, err := AttrFloat64(a); err == nil {
+			offset = f
+		}
+	}
+	return scale, offset
+}
+
+// Units returns a variable's units attribute, or "" if absent.
+func Units(v *gonc.Variable) string {
+	if a, ok := FindAttr(v.Attrs, "units"); ok {
+		return AttrString(a)
+	}
+	return ""
+}
+
+// StandardName returns a variable's standard_name attribute, or "" if
+// absent.
+func StandardName(v *gonc.Variable) string {
+	if a, ok := FindAttr(v.Attrs, "standard_name"); ok {
+		return AttrString(a)
+	}
+	return ""
+}
+
+// IsCoordinateVar reports whether v is a CF coordinate variable: one
+// whose name matches the name of its single dimension.
+func IsCoordinateVar(nc *gonc.File, v *gonc.Variable) bool {
+	return len(v.DimIDs) == 1 && nc.Dims[v.DimIDs[0]].Name == v.Name
+}
+
+// ApplyScaling converts raw values to physical units via the CF
+// unpacking formula (value = raw*scale + offset), replacing any value
+// equal to fillValue with NaN. fillValue may be nil if the variable has
+// no _FillValue attribute.
+func ApplyScaling(raw []float64, fillValue *float64, scale, offset float64) []float64 {
+	out := make([]float64, len(raw))
+	for i, r := range raw {
+		if fillValue != nil && r == *fillValue {
+			out[i] = math.NaN()
+			continue
+		}
+		out[i] = r*scale + offset
+	}
+	return out
+}
+
+// ParseUDUnitsTime parses a UDUNITS-style "units" attribute of the form
+// "<unit> since <reference-time>" (e.g. "days since 1950-01-01 00:00:00
+// UTC", the convention Argo's JULD variable uses) and returns a function
+// converting a numeric offset in that unit to an absolute time.Time.
+func ParseUDUnitsTime(units string) (func(offset float64) time.Time, error) {
+	unitPart, refPart, ok := strings.Cut(units, "since")
+	if !ok {
+		return nil, fmt.Errorf("cf: %q is not a \"<unit> since <reference>\" time unit", units)
+	}
+
+	step, err := parseTimeUnit(strings.TrimSpace(unitPart))
+	if err != nil {
+		return nil, err
+	}
+
+	ref, err := parseReferenceTime(strings.TrimSpace(refPart))
+	if err != nil {
+		return nil, err
+	}
+
+	return func(offset float64) time.Time {
+		return ref.Add(time.Duration(offset * float64(step)))
+	}, nil
+}
+
+func parseTimeUnit(unit string) (time.Duration, error) {
+	switch unit {
+	case "seconds", "second", "secs", "sec", "s":
+		return time.Second, nil
+	case "minutes", "minute", "mins", "min":
+		return time.Minute, nil
+	case "hours", "hour", "hrs", "hr":
+		return time.Hour, nil
+	case "days", "day", "d":
+		return 24 * time.Hour, nil
+	default:
+		return 0, fmt.Errorf("cf: unsupported time unit %q", unit)
+	}
+}
+
+// udunitsTimeLayouts covers the reference-time spellings actually seen in
+// the wild: space- or "T"-separated, with or without seconds, with or
+// without a trailing "UTC"/"Z".
+var udunitsTimeLayouts = []string{
+	"2006-01-02 15:04:05",
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04",
+	"2006-01-02T15:04",
+	"2006-01-02",
+}
+
+func parseReferenceTime(s string) (time.Time, error) {
+	s = strings.TrimSpace(strings.TrimSuffix(s, "UTC"))
+	s = strings.TrimSpace(strings.TrimSuffix(s, "Z"))
+
+	for _, layout := range udunitsTimeLayouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t.UTC(), nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("cf: unrecognized reference time %q", s)
+}
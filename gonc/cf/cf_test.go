@@ -0,0 +1,25 @@
+package cf
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseUDUnitsTime(t *testing.T) {
+	toTime, err := ParseUDUnitsTime("days since 1950-01-01 00:00:00 UTC")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := toTime(25202.5)
+	want := time.Date(1950, 1, 1, 0, 0, 0, 0, time.UTC).Add(25202*24*time.Hour + 12*time.Hour)
+	if !got.Equal(want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+}
+
+func TestParseUDUnitsTimeUnsupportedUnit(t *testing.T) {
+	if _, err := ParseUDUnitsTime("fortnights since 1950-01-01"); err == nil {
+		t.Fatal("expected an error for an unsupported time unit")
+	}
+}
@@ -0,0 +1,127 @@
+package gonc
+
+import "fmt"
+
+// chunkLoc is the location of one independently-stored, independently
+// codec'd chunk of a variable's data, in the same element-coordinate
+// space as the variable's shape.
+type chunkLoc struct {
+	coords []uint64
+	offset uint64
+	size   uint64
+}
+
+// readChunked serves Read for a variable whose data is stored in
+// independently-addressed, possibly-compressed chunks (the netCDF-4/HDF5
+// chunked layout). It decodes every chunk into a dense row-major buffer
+// and then slices out the requested hyperslab. That materializes the
+// whole variable even for a small read, which is wasteful for huge
+// arrays, but the chunked variables this package targets are modest-sized
+// Argo profile fields.
+func (v *Variable) readChunked(start, count, stride, dimLens []int) (interface{}, error) {
+	if len(v.unsupportedFilters) > 0 {
+		return nil, fmt.Errorf("gonc: variable %q uses unsupported filter(s) %v", v.Name, v.unsupportedFilters)
+	}
+
+	elemSize, err := ncTypeSize(v.DataType)
+	if err != nil {
+		return nil, err
+	}
+
+	ndims := len(dimLens)
+	total := 1
+	for _, n := range dimLens {
+		total *= n
+	}
+
+	elemStrides := make([]int64, ndims)
+	acc := int64(1)
+	for i := ndims - 1; i >= 0; i-- {
+		elemStrides[i] = acc
+		acc *= int64(dimLens[i])
+	}
+
+	chunkStrides := make([]int64, ndims)
+	cacc := int64(1)
+	for i := ndims - 1; i >= 0; i-- {
+		chunkStrides[i] = cacc
+		cacc *= int64(v.ChunkShape[i])
+	}
+
+	dense := make([]byte, total*elemSize)
+	for _, c := range v.chunks {
+		raw, err := v.readChunk(c)
+		if err != nil {
+			return nil, err
+		}
+
+		// A chunk at the array's edge may overhang the variable's actual
+		// shape; shape clips it to however many elements really exist
+		// there.
+		shape := make([]int, ndims)
+		chunkElems := 1
+		for i := 0; i < ndims; i++ {
+			shape[i] = int(v.ChunkShape[i])
+			if rem := dimLens[i] - int(c.coords[i]); rem < shape[i] {
+				shape[i] = rem
+			}
+			chunkElems *= shape[i]
+		}
+
+		idx := make([]int, ndims)
+		for n := 0; n < chunkElems; n++ {
+			srcOff, dstOff := int64(0), int64(0)
+			for d := 0; d < ndims; d++ {
+				srcOff += int64(idx[d]) * chunkStrides[d]
+				dstOff += (int64(c.coords[d]) + int64(idx[d])) * elemStrides[d]
+			}
+			copy(dense[dstOff*int64(elemSize):(dstOff+1)*int64(elemSize)],
+				raw[srcOff*int64(elemSize):(srcOff+1)*int64(elemSize)])
+
+			for d := ndims - 1; d >= 0; d-- {
+				idx[d]++
+				if idx[d] < shape[d] {
+					break
+				}
+				idx[d] = 0
+			}
+		}
+	}
+
+	sliceTotal := 1
+	for _, n := range count {
+		sliceTotal *= n
+	}
+
+	raw := make([]byte, sliceTotal*elemSize)
+	idx := make([]int, ndims)
+	for n := 0; n < sliceTotal; n++ {
+		off := int64(0)
+		for d := 0; d < ndims; d++ {
+			off += int64(start[d]+idx[d]*stride[d]) * elemStrides[d]
+		}
+		copy(raw[n*elemSize:(n+1)*elemSize], dense[off*int64(elemSize):(off+1)*int64(elemSize)])
+
+		for d := ndims - 1; d >= 0; d-- {
+			idx[d]++
+			if idx[d] < count[d] {
+				break
+			}
+			idx[d] = 0
+		}
+	}
+
+	return decodeSlab(v.DataType, raw)
+}
+
+// readChunk fetches and decodes one chunk's raw bytes.
+func (v *Variable) readChunk(c chunkLoc) ([]byte, error) {
+	buf := make([]byte, c.size)
+	if _, err := v.nc.r.ReadAt(buf, int64(c.offset)); err != nil {
+		return nil, err
+	}
+	if v.Codec == nil {
+		return buf, nil
+	}
+	return v.Codec.Decode(buf)
+}
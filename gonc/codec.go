@@ -0,0 +1,132 @@
+package gonc
+
+import (
+	"bytes"
+	"compress/zlib"
+	"io"
+	"sync"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/ulikunitz/xz"
+)
+
+// Codec decodes and encodes the raw bytes of one variable chunk. It
+// mirrors the compression-plugin pattern HDF5 and Zarr both use: a
+// variable records which codec its chunks were written with, and the
+// read/write path looks it up by ID rather than hard-coding a format.
+type Codec interface {
+	Decode(src []byte) ([]byte, error)
+	Encode(src []byte) ([]byte, error)
+	ID() uint16
+}
+
+// Built-in codec IDs. Deflate is the HDF5 deflate filter's registered
+// filter ID (1); per the HDF5 spec it's a raw zlib (RFC 1950) stream, not
+// gzip (RFC 1952) framing. ZSTD and XZ have no single standardized HDF5
+// filter ID — these match the IDs the commonly used hdf5plugin/h5z-zstd
+// and xz HDF5 filter plugins register.
+const (
+	CodecDeflate uint16 = 1
+	CodecZSTD    uint16 = 32015
+	CodecXZ      uint16 = 32008
+)
+
+var (
+	codecsMu sync.RWMutex
+	codecs   = map[uint16]Codec{
+		CodecDeflate: deflateCodec{},
+		CodecZSTD:    zstdCodec{},
+		CodecXZ:      xzCodec{},
+	}
+)
+
+// RegisterCodec makes c available for decoding/encoding variable chunks
+// tagged with its ID, alongside the built-in deflate/zstd/xz codecs. Use it
+// to plug in formats like blosc or lz4 that this package doesn't
+// implement itself.
+func RegisterCodec(c Codec) {
+	codecsMu.Lock()
+	defer codecsMu.Unlock()
+	codecs[c.ID()] = c
+}
+
+func codecByID(id uint16) (Codec, bool) {
+	codecsMu.RLock()
+	defer codecsMu.RUnlock()
+	c, ok := codecs[id]
+	return c, ok
+}
+
+type deflateCodec struct{}
+
+func (deflateCodec) ID() uint16 { return CodecDeflate }
+
+func (deflateCodec) Decode(src []byte) ([]byte, error) {
+	zr, err := zlib.NewReader(bytes.NewReader(src))
+	if err != nil {
+		return nil, err
+	}
+	defer zr.Close()
+	return io.ReadAll(zr)
+}
+
+func (deflateCodec) Encode(src []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	zw := zlib.NewWriter(&buf)
+	if _, err := zw.Write(src); err != nil {
+		return nil, err
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+type zstdCodec struct{}
+
+func (zstdCodec) ID() uint16 { return CodecZSTD }
+
+func (zstdCodec) Decode(src []byte) ([]byte, error) {
+	dec, err := zstd.NewReader(nil)
+	if err != nil {
+		return nil, err
+	}
+	defer dec.Close()
+	return dec.DecodeAll(src, nil)
+}
+
+func (zstdCodec) Encode(src []byte) ([]byte, error) {
+	enc, err := zstd.NewWriter(nil)
+	if err != nil {
+		return nil, err
+	}
+	defer enc.Close()
+	return enc.EncodeAll(src, nil), nil
+}
+
+type xzCodec struct{}
+
+func (xzCodec) ID() uint16 { return CodecXZ }
+
+func (xzCodec) Decode(src []byte) ([]byte, error) {
+	r, err := xz.NewReader(bytes.NewReader(src))
+	if err != nil {
+		return nil, err
+	}
+	return io.ReadAll(r)
+}
+
+func (xzCodec) Encode(src []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	w, err := xz.NewWriter(&buf)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := w.Write(src); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,92 @@
+package hdf5
+
+import "fmt"
+
+// rawChunk is one leaf entry from a "chunked raw data node" B-tree (node
+// type 1): the file location of one chunk's (possibly filtered) bytes and
+// its coordinate, in dataset-element units, within the full array.
+type rawChunk struct {
+	coords []uint64
+	addr   uint64
+	size   uint64
+}
+
+// readChunkBTree walks a version-1 B-tree of chunked-raw-data-node type
+// rooted at addr, returning every chunk reachable from it. rank is the
+// number of coordinate dimensions encoded in each key: the dataspace rank
+// plus the trailing "dataset element size" dimension chunked storage
+// always appends to the Data Layout message's dimension list.
+func (rd *reader) readChunkBTree(addr uint64, rank int) ([]rawChunk, error) {
+	var out []rawChunk
+	if err := rd.walkChunkBTreeNode(addr, rank, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (rd *reader) walkChunkBTreeNode(nodeAddr uint64, rank int, out *[]rawChunk) error {
+	sig, err := rd.readAt(int64(nodeAddr), 4)
+	if err != nil {
+		return err
+	}
+	if string(sig) != "TREE" {
+		return fmt.Errorf("hdf5: expected TREE node at %#x", nodeAddr)
+	}
+
+	nodeType, err := rd.readAt(int64(nodeAddr)+4, 1)
+	if err != nil {
+		return err
+	}
+	if nodeType[0] != 1 {
+		return fmt.Errorf("hdf5: unsupported B-tree node type %d (only raw-chunk nodes are, here)", nodeType[0])
+	}
+
+	level, err := rd.readAt(int64(nodeAddr)+5, 1)
+	if err != nil {
+		return err
+	}
+
+	entriesBuf, err := rd.readAt(int64(nodeAddr)+6, 2)
+	if err != nil {
+		return err
+	}
+	entriesUsed := int(beUint(entriesBuf))
+
+	// Header: TREE(4) + type(1) + level(1) + entries(2) + left/right
+	// sibling (offset each), then entriesUsed keys each immediately
+	// followed by a child pointer: K0 C0 K1 C1 ... K(n-1) C(n-1). A raw
+	// chunk key is chunk size (4) + filter mask (4) + rank coordinates
+	// (sz.length bytes each).
+	pos := int64(nodeAddr) + 8 + 2*int64(rd.sz.offset)
+	keySize := int64(4 + 4 + rank*rd.sz.length)
+
+	for i := 0; i < entriesUsed; i++ {
+		keyBuf, err := rd.readAt(pos, int(keySize))
+		if err != nil {
+			return err
+		}
+		pos += keySize
+
+		child, err := rd.readOffset(pos)
+		if err != nil {
+			return err
+		}
+		pos += int64(rd.sz.offset)
+
+		size := beUint(keyBuf[0:4])
+		coords := make([]uint64, rank)
+		off := 8
+		for d := 0; d < rank; d++ {
+			coords[d] = beUint(keyBuf[off : off+rd.sz.length])
+			off += rd.sz.length
+		}
+
+		if level[0] == 0 {
+			*out = append(*out, rawChunk{coords: coords, addr: child, size: size})
+		} else if err := rd.walkChunkBTreeNode(child, rank, out); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,92 @@
+package hdf5
+
+import "fmt"
+
+// resolveDimensionList decodes a DIMENSION_LIST attribute's raw value: one
+// variable-length sequence of object references per axis (in Shape order),
+// each naming the dimension-scale dataset attached to that axis. It returns
+// the referenced dataset's object header address per axis, 0 where an axis
+// has no attached scale.
+func (rd *reader) resolveDimensionList(values []byte, rank int) ([]uint64, error) {
+	// Each axis's VL sequence is recorded as: sequence length (4 bytes),
+	// global heap collection address (sz.offset bytes), heap object index
+	// (4 bytes).
+	descSize := 8 + rd.sz.offset
+	addrs := make([]uint64, rank)
+
+	for i := 0; i < rank; i++ {
+		off := i * descSize
+		if off+descSize > len(values) {
+			return nil, fmt.Errorf("hdf5: truncated DIMENSION_LIST value for axis %d", i)
+		}
+
+		length := beUint(values[off : off+4])
+		collAddr := beUint(values[off+4 : off+4+rd.sz.offset])
+		heapIndex := uint16(beUint(values[off+4+rd.sz.offset : off+8+rd.sz.offset]))
+		if length == 0 || collAddr == 0 {
+			continue
+		}
+
+		data, err := rd.readGlobalHeapObject(collAddr, heapIndex)
+		if err != nil {
+			return nil, err
+		}
+		if len(data) < rd.sz.offset {
+			return nil, fmt.Errorf("hdf5: truncated object reference for axis %d", i)
+		}
+		addrs[i] = beUint(data[:rd.sz.offset])
+	}
+
+	return addrs, nil
+}
+
+// readGlobalHeapObject reads one object's data out of the Global Heap
+// Collection at collectionAddr: "GCOL" signature (4), version (1), reserved
+// (3), collection size (length), then heap objects back to back (index (2),
+// reference count (2), reserved (4), object size (length), data padded to
+// an 8-byte boundary), terminated by an object with index 0.
+func (rd *reader) readGlobalHeapObject(collectionAddr uint64, index uint16) ([]byte, error) {
+	sig, err := rd.readAt(int64(collectionAddr), 4)
+	if err != nil {
+		return nil, err
+	}
+	if string(sig) != "GCOL" {
+		return nil, fmt.Errorf("hdf5: expected GCOL collection at %#x", collectionAddr)
+	}
+
+	collSize, err := rd.readLength(int64(collectionAddr) + 8)
+	if err != nil {
+		return nil, err
+	}
+	end := int64(collectionAddr) + int64(collSize)
+
+	pos := int64(collectionAddr) + 8 + int64(rd.sz.length)
+	for pos < end {
+		idxBuf, err := rd.readAt(pos, 2)
+		if err != nil {
+			return nil, err
+		}
+		idx := uint16(beUint(idxBuf))
+		if idx == 0 {
+			break
+		}
+
+		objSize, err := rd.readLength(pos + 8)
+		if err != nil {
+			return nil, err
+		}
+		dataOff := pos + 8 + int64(rd.sz.length)
+
+		if idx == index {
+			return rd.readAt(dataOff, int(objSize))
+		}
+
+		padded := objSize
+		if pad := padded % 8; pad != 0 {
+			padded += 8 - pad
+		}
+		pos = dataOff + int64(padded)
+	}
+
+	return nil, fmt.Errorf("hdf5: global heap object %d not found in collection at %#x", index, collectionAddr)
+}
@@ -0,0 +1,492 @@
+// Package hdf5 implements just enough of the HDF5 file format to read the
+// group/dataset/attribute layout that netCDF-4 files use. It targets the
+// "classic model" HDF5 1.8-style layout netCDF-4 actually writes in
+// practice (version-0 superblock, old-style B-tree/local-heap groups,
+// version-1 object headers, contiguous or version-1-B-tree-indexed chunked
+// dataset storage): enough to walk a typical Argo netCDF-4 file's
+// dimensions, variables and attributes. It is not a general HDF5 reader —
+// new-style (fractal heap / link-message) groups and B-tree-v2 indices are
+// out of scope and reported as errors rather than guessed at.
+package hdf5
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Signature is the 8-byte magic every HDF5 file starts with.
+var Signature = []byte{0x89, 'H', 'D', 'F', '\r', '\n', 0x1a, '\n'}
+
+// Dim is a netCDF dimension recovered from an HDF5 dimension-scale dataset
+// (one whose CLASS attribute is "DIMENSION_SCALE").
+type Dim struct {
+	Name   string
+	Length uint64
+
+	// Addr is the dimension-scale dataset's own object header address, so a
+	// Dataset's DimAddrs (resolved from its DIMENSION_LIST attribute) can be
+	// matched back to the Dim that actually backs each axis.
+	Addr uint64
+}
+
+// Attr is an HDF5 attribute attached to a dataset or group.
+type Attr struct {
+	Name   string
+	Type   uint32 // a gonc NC_* constant, best-effort mapped from the HDF5 datatype
+	Values []byte
+}
+
+// Dataset is a netCDF variable recovered from an HDF5 dataset.
+type Dataset struct {
+	Name     string
+	Shape    []uint64
+	DataType uint32 // a gonc NC_* constant
+	Offset   uint64
+	Size     uint64
+	Attrs    []Attr
+
+	// ChunkShape, FilterIDs and Chunks are populated only for datasets
+	// using the chunked storage layout; Offset/Size are meaningless for
+	// them (each chunk has its own location instead). FilterIDs are
+	// HDF5's registered filter IDs, in application order, so a caller can
+	// match them against a codec registry.
+	ChunkShape []uint64
+	FilterIDs  []uint16
+	Chunks     []Chunk
+
+	// DimAddrs is the dimension-scale object header address attached to
+	// each axis (in Shape order), resolved from a DIMENSION_LIST attribute.
+	// It is nil if DIMENSION_LIST is absent; otherwise an entry is 0 where
+	// that axis's reference sequence is empty. A reference that can't be
+	// resolved makes Parse fail.
+	DimAddrs []uint64
+}
+
+// Chunk is one independently-stored, independently-filtered piece of a
+// chunked dataset's data.
+type Chunk struct {
+	// Coords is the chunk's first element's coordinate in the dataset,
+	// one entry per dataspace dimension (in the same order as Shape).
+	Coords []uint64
+	Offset uint64
+	Size   uint64
+}
+
+// Group is the flattened content of an HDF5 file's object graph: every
+// dimension-scale dataset found becomes a Dim, every other dataset becomes
+// a Dataset. Nested groups are walked and merged into the same flat lists,
+// since typical Argo files keep everything in the root group.
+type Group struct {
+	Dims     []Dim
+	Datasets []Dataset
+	Attrs    []Attr
+}
+
+// NC_* mirrors gonc's external type constants; duplicated here so this
+// package doesn't need to import gonc (which imports this package).
+const (
+	ncByte   = 1
+	ncChar   = 2
+	ncShort  = 3
+	ncInt    = 4
+	ncFloat  = 5
+	ncDouble = 6
+)
+
+// sizes holds the superblock's "size of offsets" / "size of lengths",
+// almost always 8 on files written by a 64-bit HDF5 library.
+type sizes struct {
+	offset int
+	length int
+}
+
+type reader struct {
+	r  io.ReaderAt
+	sz sizes
+}
+
+// Parse reads an HDF5 file and flattens its object graph into a Group.
+func Parse(r io.ReaderAt, size int64) (*Group, error) {
+	hdr := make([]byte, 9)
+	if _, err := r.ReadAt(hdr, 0); err != nil {
+		return nil, err
+	}
+	for i, b := range Signature {
+		if hdr[i] != b {
+			return nil, errors.New("hdf5: bad signature")
+		}
+	}
+	if sbVersion := hdr[8]; sbVersion != 0 {
+		return nil, fmt.Errorf("hdf5: superblock version %d not supported (only version 0, the HDF5 1.8 default, is)", sbVersion)
+	}
+
+	sizeBuf := make([]byte, 2)
+	if _, err := r.ReadAt(sizeBuf, 13); err != nil {
+		return nil, err
+	}
+	sz := sizes{offset: int(sizeBuf[0]), length: int(sizeBuf[1])}
+
+	rd := &reader{r: r, sz: sz}
+
+	// Superblock v0 layout after the 20-byte fixed prefix (8-byte
+	// signature, version bytes at 8-12, size-of-offsets/lengths at 13-14,
+	// a reserved byte at 15, group leaf/internal K at 16-19): consistency
+	// flags (4 bytes), then base address, free-space address, EOF
+	// address and driver-info address (each sz.offset bytes), then the
+	// root group's symbol table entry.
+	addrsOff := int64(24)
+	rootEntryOff := addrsOff + 4*int64(sz.offset)
+
+	entry, _, err := rd.readSymbolTableEntry(rootEntryOff, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	g := &Group{}
+	visited := map[uint64]bool{}
+	if err := rd.walkObject(entry.objHeaderAddr, "", true, g, visited); err != nil {
+		return nil, err
+	}
+	return g, nil
+}
+
+func (rd *reader) readAt(off int64, n int) ([]byte, error) {
+	buf := make([]byte, n)
+	if _, err := rd.r.ReadAt(buf, off); err != nil {
+		return nil, err
+	}
+	return buf, nil
+}
+
+func beUint(b []byte) uint64 {
+	var v uint64
+	for i := len(b) - 1; i >= 0; i-- {
+		v = v<<8 | uint64(b[i])
+	}
+	return v
+}
+
+func (rd *reader) readOffset(off int64) (uint64, error) {
+	buf, err := rd.readAt(off, rd.sz.offset)
+	if err != nil {
+		return 0, err
+	}
+	return beUint(buf), nil
+}
+
+func (rd *reader) readLength(off int64) (uint64, error) {
+	buf, err := rd.readAt(off, rd.sz.length)
+	if err != nil {
+		return 0, err
+	}
+	return beUint(buf), nil
+}
+
+// symbolTableEntry is one link inside a group: a name (resolved via the
+// owning group's local heap) and the address of the linked object's
+// header.
+type symbolTableEntry struct {
+	objHeaderAddr uint64
+}
+
+// symbolTableEntrySize is link-name-offset + object-header-address +
+// cache-type (4 bytes) + reserved (4 bytes) + 16-byte scratch-pad.
+func (rd *reader) symbolTableEntrySize() int64 {
+	return int64(rd.sz.length+rd.sz.offset) + 4 + 4 + 16
+}
+
+// readSymbolTableEntry reads one Symbol Table Entry at off, resolving its
+// link name against the local heap at heapAddr (pass 0 for the root entry,
+// which has no name of its own).
+func (rd *reader) readSymbolTableEntry(off int64, heapAddr uint64) (symbolTableEntry, string, error) {
+	linkNameOffset, err := rd.readLength(off)
+	if err != nil {
+		return symbolTableEntry{}, "", err
+	}
+	off += int64(rd.sz.length)
+
+	objHeaderAddr, err := rd.readOffset(off)
+	if err != nil {
+		return symbolTableEntry{}, "", err
+	}
+
+	var name string
+	if heapAddr != 0 {
+		name, err = rd.readHeapString(heapAddr, linkNameOffset)
+		if err != nil {
+			return symbolTableEntry{}, "", err
+		}
+	}
+
+	return symbolTableEntry{objHeaderAddr: objHeaderAddr}, name, nil
+}
+
+// readHeapString reads a null-terminated string out of a local heap's data
+// segment at the given offset.
+func (rd *reader) readHeapString(heapAddr uint64, strOffset uint64) (string, error) {
+	// Local heap header: "HEAP" signature (4), version (1), reserved (3),
+	// data segment size (length), free-list head offset (length), data
+	// segment address (offset).
+	dataSizeOff := int64(heapAddr) + 8
+	dataAddrOff := dataSizeOff + 2*int64(rd.sz.length)
+	dataAddr, err := rd.readOffset(dataAddrOff)
+	if err != nil {
+		return "", err
+	}
+
+	// Read a generous chunk and trim at the NUL; heap strings are short
+	// link names, so this avoids a second round trip to learn the length.
+	buf := make([]byte, 256)
+	n, err := rd.r.ReadAt(buf, int64(dataAddr)+int64(strOffset))
+	if err != nil {
+		// Tolerate reads that walk past EOF by falling back to whatever
+		// was actually available.
+		if n == 0 {
+			return "", err
+		}
+	}
+	buf = buf[:n]
+	if i := strings.IndexByte(string(buf), 0); i >= 0 {
+		return string(buf[:i]), nil
+	}
+	return string(buf), nil
+}
+
+// walkGroupBTree walks a version-1 B-tree of group-node type, returning
+// every (name, symbolTableEntry) pair reachable from it.
+func (rd *reader) walkGroupBTree(btreeAddr, heapAddr uint64) (map[string]symbolTableEntry, error) {
+	out := map[string]symbolTableEntry{}
+	if err := rd.walkGroupBTreeNode(btreeAddr, heapAddr, out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (rd *reader) walkGroupBTreeNode(nodeAddr, heapAddr uint64, out map[string]symbolTableEntry) error {
+	sig, err := rd.readAt(int64(nodeAddr), 4)
+	if err != nil {
+		return err
+	}
+	if string(sig) != "TREE" {
+		return fmt.Errorf("hdf5: expected TREE node at %#x", nodeAddr)
+	}
+
+	nodeType, err := rd.readAt(int64(nodeAddr)+4, 1)
+	if err != nil {
+		return err
+	}
+	if nodeType[0] != 0 {
+		return fmt.Errorf("hdf5: unsupported B-tree node type %d (only group nodes are)", nodeType[0])
+	}
+
+	level, err := rd.readAt(int64(nodeAddr)+5, 1)
+	if err != nil {
+		return err
+	}
+
+	entriesBuf, err := rd.readAt(int64(nodeAddr)+6, 2)
+	if err != nil {
+		return err
+	}
+	entriesUsed := int(beUint(entriesBuf))
+
+	// Header: TREE(4) + type(1) + level(1) + entries(2) + left sibling
+	// (offset) + right sibling (offset), then entriesUsed+1 keys
+	// interleaved with entriesUsed child pointers: K0 C0 K1 C1 ... Kn.
+	pos := int64(nodeAddr) + 8 + 2*int64(rd.sz.offset)
+	keySize := int64(rd.sz.length)
+
+	pos += keySize // skip K0; keys are only needed to narrow a search, not to enumerate every entry
+	for i := 0; i < entriesUsed; i++ {
+		child, err := rd.readOffset(pos)
+		if err != nil {
+			return err
+		}
+		pos += int64(rd.sz.offset) + keySize
+
+		if level[0] == 0 {
+			if err := rd.walkSymbolTableNode(child, heapAddr, out); err != nil {
+				return err
+			}
+		} else {
+			if err := rd.walkGroupBTreeNode(child, heapAddr, out); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func (rd *reader) walkSymbolTableNode(nodeAddr, heapAddr uint64, out map[string]symbolTableEntry) error {
+	sig, err := rd.readAt(int64(nodeAddr), 4)
+	if err != nil {
+		return err
+	}
+	if string(sig) != "SNOD" {
+		return fmt.Errorf("hdf5: expected SNOD node at %#x", nodeAddr)
+	}
+
+	countBuf, err := rd.readAt(int64(nodeAddr)+6, 2)
+	if err != nil {
+		return err
+	}
+	count := int(beUint(countBuf))
+
+	pos := int64(nodeAddr) + 8
+	entrySize := rd.symbolTableEntrySize()
+	for i := 0; i < count; i++ {
+		entry, name, err := rd.readSymbolTableEntry(pos, heapAddr)
+		if err != nil {
+			return err
+		}
+		out[name] = entry
+		pos += entrySize
+	}
+
+	return nil
+}
+
+// walkObject parses the object header at addr. If it is a group (it has a
+// Symbol Table message), it recurses into every member, naming each by its
+// link in the parent group. If it is a dataset, it is classified as a
+// dimension scale or a variable and appended to g.
+func (rd *reader) walkObject(addr uint64, name string, isRoot bool, g *Group, visited map[uint64]bool) error {
+	if visited[addr] {
+		return nil
+	}
+	visited[addr] = true
+
+	msgs, err := rd.readObjectHeader(int64(addr))
+	if err != nil {
+		return err
+	}
+
+	var (
+		shape      []uint64
+		dtype      uint32
+		layout     dataLayout
+		haveLayout bool
+		filterIDs  []uint16
+		attrs      []Attr
+		btreeAddr  uint64
+		heapAddr   uint64
+		isGroup    bool
+	)
+
+	for _, m := range msgs {
+		switch m.msgType {
+		case msgDataspace:
+			if shape, err = parseDataspace(m.data, rd.sz); err != nil {
+				return err
+			}
+		case msgDatatype:
+			if dtype, err = parseDatatype(m.data); err != nil {
+				return err
+			}
+		case msgDataLayout:
+			if layout, err = parseDataLayout(m.data, rd.sz); err != nil {
+				return err
+			}
+			haveLayout = true
+		case msgFilterPipeline:
+			if filterIDs, err = parseFilterPipeline(m.data); err != nil {
+				return err
+			}
+		case msgAttribute:
+			a, err := parseAttribute(m.data)
+			if err != nil {
+				return err
+			}
+			attrs = append(attrs, a)
+		case msgSymbolTable:
+			isGroup = true
+			btreeAddr = beUint(m.data[0:rd.sz.offset])
+			heapAddr = beUint(m.data[rd.sz.offset : 2*rd.sz.offset])
+		}
+	}
+
+	if isGroup {
+		if isRoot {
+			g.Attrs = attrs
+		}
+		members, err := rd.walkGroupBTree(btreeAddr, heapAddr)
+		if err != nil {
+			return err
+		}
+		for childName, e := range members {
+			if err := rd.walkObject(e.objHeaderAddr, childName, false, g, visited); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	if !haveLayout || layout.class == 0 {
+		// Not a group and no data we can locate (no layout message, or a
+		// compact layout we don't support): nothing to surface as a dim
+		// or var.
+		return nil
+	}
+
+	isDimScale := false
+	for _, a := range attrs {
+		if a.Name == "CLASS" && strings.Contains(string(a.Values), "DIMENSION_SCALE") {
+			isDimScale = true
+		}
+	}
+
+	if isDimScale {
+		var length uint64
+		if len(shape) > 0 {
+			length = shape[0]
+		}
+		g.Dims = append(g.Dims, Dim{Name: name, Length: length, Addr: addr})
+		return nil
+	}
+
+	ds := Dataset{
+		Name:     name,
+		Shape:    shape,
+		DataType: dtype,
+		Attrs:    attrs,
+	}
+
+	for _, a := range attrs {
+		if a.Name != "DIMENSION_LIST" {
+			continue
+		}
+		dimAddrs, err := rd.resolveDimensionList(a.Values, len(shape))
+		if err != nil {
+			return err
+		}
+		ds.DimAddrs = dimAddrs
+	}
+
+	if layout.class == 2 {
+		rank := len(layout.chunkDims)
+		raw, err := rd.readChunkBTree(layout.btreeAddr, rank)
+		if err != nil {
+			return err
+		}
+
+		// chunkDims' trailing entry is the per-element byte size HDF5
+		// appends to every chunked layout message, not a dataset
+		// dimension; drop it here so ChunkShape and each Chunk's Coords
+		// line up one-to-one with Shape.
+		ds.ChunkShape = append([]uint64(nil), layout.chunkDims[:rank-1]...)
+		ds.FilterIDs = filterIDs
+		ds.Chunks = make([]Chunk, len(raw))
+		for i, c := range raw {
+			ds.Chunks[i] = Chunk{Coords: c.coords[:rank-1], Offset: c.addr, Size: c.size}
+		}
+	} else {
+		ds.Offset = layout.addr
+		ds.Size = layout.size
+	}
+
+	g.Datasets = append(g.Datasets, ds)
+	return nil
+}
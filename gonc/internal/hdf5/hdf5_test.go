@@ -0,0 +1,376 @@
+package hdf5
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+	"testing"
+)
+
+// fixtureBuilder assembles a minimal HDF5 v0 file byte-by-byte: just enough
+// of the format (old-style B-tree/local-heap group, version-1 object
+// headers, contiguous storage, version-1 attributes) for this package to
+// parse. Every address is simply "where we are in buf right now", recorded
+// as sections are appended in dependency order (children before the
+// structures that reference them).
+type fixtureBuilder struct {
+	buf bytes.Buffer
+}
+
+func (b *fixtureBuilder) addr() uint64 { return uint64(b.buf.Len()) }
+
+func (b *fixtureBuilder) writeBytes(p []byte) { b.buf.Write(p) }
+
+// HDF5 stores its header fields little-endian (see beUint in hdf5.go),
+// unlike netCDF classic's big-endian header.
+func putLE16(v uint16) []byte {
+	p := make([]byte, 2)
+	binary.LittleEndian.PutUint16(p, v)
+	return p
+}
+
+func putLE32(v uint32) []byte {
+	p := make([]byte, 4)
+	binary.LittleEndian.PutUint32(p, v)
+	return p
+}
+
+func putLE64(v uint64) []byte {
+	p := make([]byte, 8)
+	binary.LittleEndian.PutUint64(p, v)
+	return p
+}
+
+// buildAttribute encodes a version-1 Attribute message body: an 8-byte
+// header (version, reserved, name/datatype/dataspace sizes) followed by
+// each of those three sub-messages padded to an 8-byte boundary, then the
+// raw value bytes.
+func buildAttribute(name string, dtypeClass byte, dims []uint64, value []byte) []byte {
+	nameBytes := append([]byte(name), 0)
+	nameField := make([]byte, pad8(len(nameBytes)))
+	copy(nameField, nameBytes)
+
+	dt := make([]byte, 8)
+	dt[0] = dtypeClass
+	dtField := make([]byte, pad8(len(dt)))
+	copy(dtField, dt)
+
+	ds := make([]byte, 8+8*len(dims))
+	ds[0] = 1 // dataspace version
+	ds[1] = byte(len(dims))
+	for i, d := range dims {
+		copy(ds[8+8*i:], putLE64(d))
+	}
+	dsField := make([]byte, pad8(len(ds)))
+	copy(dsField, ds)
+
+	var out []byte
+	hdr := make([]byte, 8)
+	hdr[0] = 1
+	copy(hdr[2:4], putLE16(uint16(len(nameBytes))))
+	copy(hdr[4:6], putLE16(uint16(len(dt))))
+	copy(hdr[6:8], putLE16(uint16(len(ds))))
+	out = append(out, hdr...)
+	out = append(out, nameField...)
+	out = append(out, dtField...)
+	out = append(out, dsField...)
+	out = append(out, value...)
+	return out
+}
+
+// buildMessage wraps a message body with its 8-byte header: type (2), size
+// (2), flags (1), 3 reserved bytes.
+func buildMessage(msgType uint16, data []byte) []byte {
+	hdr := make([]byte, 8)
+	copy(hdr[0:2], putLE16(msgType))
+	copy(hdr[2:4], putLE16(uint16(len(data))))
+	return append(hdr, data...)
+}
+
+// buildObjectHeader assembles a version-1 object header from already
+// wrapped messages (each produced by buildMessage).
+func buildObjectHeader(msgs [][]byte) []byte {
+	var body []byte
+	for _, m := range msgs {
+		body = append(body, m...)
+	}
+
+	prefix := make([]byte, 16)
+	prefix[0] = 1 // version
+	copy(prefix[2:4], putLE16(uint16(len(msgs))))
+	copy(prefix[8:12], putLE32(uint32(len(body))))
+	return append(prefix, body...)
+}
+
+// dimScaleAttr and datatypeMsg are the recurring pieces every dataset in
+// the fixture needs.
+func dimScaleAttr() []byte {
+	return buildMessage(msgAttribute, buildAttribute("CLASS", 3, nil, []byte("DIMENSION_SCALE")))
+}
+
+func dataspaceMsg(dims []uint64) []byte {
+	data := make([]byte, 8+8*len(dims))
+	data[0] = 1 // version
+	data[1] = byte(len(dims))
+	for i, d := range dims {
+		copy(data[8+8*i:], putLE64(d))
+	}
+	return buildMessage(msgDataspace, data)
+}
+
+func datatypeMsg(class byte, size uint32) []byte {
+	data := make([]byte, 8)
+	data[0] = class
+	copy(data[4:8], putLE32(size))
+	return buildMessage(msgDatatype, data)
+}
+
+func contiguousLayoutMsg(addr, size uint64) []byte {
+	data := make([]byte, 2+8+8)
+	data[0] = 3 // layout message version
+	data[1] = 1 // contiguous
+	copy(data[2:10], putLE64(addr))
+	copy(data[10:18], putLE64(size))
+	return buildMessage(msgDataLayout, data)
+}
+
+// buildDimensionListValue encodes the raw value of a DIMENSION_LIST
+// attribute for a single-axis variable: one VL descriptor (sequence length,
+// global heap collection address, heap object index) naming the
+// dimension-scale dataset attached to that axis.
+func buildDimensionListValue(collAddr uint64, heapIndex uint16) []byte {
+	v := make([]byte, 16)
+	copy(v[0:4], putLE32(1))
+	copy(v[4:12], putLE64(collAddr))
+	copy(v[12:16], putLE32(uint32(heapIndex)))
+	return v
+}
+
+// buildGlobalHeapCollection wraps a single object reference (plus the
+// required null-terminator object) in a Global Heap Collection, returning
+// its bytes ready to be placed at a fixed address.
+func buildGlobalHeapCollection(refAddr uint64) []byte {
+	var body []byte
+	body = append(body, putLE16(1)...)       // heap object index
+	body = append(body, putLE16(1)...)       // reference count
+	body = append(body, make([]byte, 4)...)  // reserved
+	body = append(body, putLE64(8)...)       // object size
+	body = append(body, putLE64(refAddr)...) // object data: the reference itself
+	body = append(body, putLE16(0)...)       // terminator: heap object index 0
+	body = append(body, make([]byte, 2)...)  // terminator: reference count
+	body = append(body, make([]byte, 4)...)  // terminator: reserved
+	body = append(body, putLE64(0)...)       // terminator: object size
+
+	hdr := []byte("GCOL")
+	hdr = append(hdr, 1, 0, 0, 0) // version + reserved
+	hdr = append(hdr, putLE64(uint64(8+8+len(body)))...)
+	return append(hdr, body...)
+}
+
+// buildArgoLikeFixture assembles a tiny netCDF-4-style HDF5 file with two
+// same-length dimension scales ("a" and "b") so length-based axis matching
+// alone can't tell them apart, a float variable "v" bound to "b" via a
+// DIMENSION_LIST reference, and a string-class (NC_CHAR) variable "flag"
+// bound to "a" by ordinary length matching (no DIMENSION_LIST).
+func buildArgoLikeFixture(t *testing.T) []byte {
+	t.Helper()
+	var b fixtureBuilder
+
+	// Superblock: signature (8) + version info (5, byte 8 = superblock
+	// version 0) + size-of-offsets/size-of-lengths (1 each, at 13-14) + a
+	// reserved byte (15) + group leaf/internal K (4, 16-19) + consistency
+	// flags (4, 20-23) = 24 bytes (addrsOff), then base/free-space/EOF/
+	// driver-info addresses (4*8=32), then a 40-byte root symbol table
+	// entry.
+	const sz = 8
+	b.writeBytes(Signature)
+	b.writeBytes(make([]byte, 5)) // version info; byte 8 (superblock version) left 0
+	b.writeBytes([]byte{sz, sz})  // size-of-offsets, size-of-lengths
+	b.writeBytes(make([]byte, 1)) // reserved
+	b.writeBytes(make([]byte, 4)) // group leaf/internal K
+	b.writeBytes(make([]byte, 4)) // consistency flags
+	b.writeBytes(make([]byte, 8)) // base address
+	b.writeBytes(make([]byte, 8)) // free-space address
+	eofOff := b.addr()
+	b.writeBytes(make([]byte, 8)) // EOF address (patched once file size is known)
+	b.writeBytes(make([]byte, 8)) // driver info address
+	rootEntryOff := b.addr()
+	b.writeBytes(make([]byte, 40)) // root symbol table entry (patched below)
+
+	// Local heap for the root group's link names.
+	heapHeaderAddr := b.addr()
+	heapDataAddr := heapHeaderAddr + 32
+	names := []string{"a\x00", "b\x00", "v\x00", "flag\x00"}
+	var heapData []byte
+	nameOffsets := map[string]uint64{}
+	for _, n := range names {
+		nameOffsets[n[:len(n)-1]] = uint64(len(heapData))
+		heapData = append(heapData, n...)
+	}
+	b.writeBytes([]byte("HEAP"))
+	b.writeBytes([]byte{1, 0, 0, 0})
+	b.writeBytes(putLE64(uint64(len(heapData))))
+	b.writeBytes(putLE64(1))
+	b.writeBytes(putLE64(heapDataAddr))
+	b.writeBytes(heapData)
+
+	// Dimension-scale dataset "a": 1-D, length 2, NC_DOUBLE.
+	aData := append(putLE64fl(10.0), putLE64fl(20.0)...)
+	aHeaderAddr := b.addr()
+	aDataAddr := aHeaderAddr + uint64(len(buildObjectHeader([][]byte{
+		dataspaceMsg([]uint64{2}), datatypeMsg(1, 8), contiguousLayoutMsg(0, 16), dimScaleAttr(),
+	})))
+	b.writeBytes(buildObjectHeader([][]byte{
+		dataspaceMsg([]uint64{2}), datatypeMsg(1, 8), contiguousLayoutMsg(aDataAddr, 16), dimScaleAttr(),
+	}))
+	b.writeBytes(aData)
+
+	// Dimension-scale dataset "b": 1-D, length 2, NC_DOUBLE.
+	bData := append(putLE64fl(100.0), putLE64fl(200.0)...)
+	bHeaderAddr := b.addr()
+	bDataAddr := bHeaderAddr + uint64(len(buildObjectHeader([][]byte{
+		dataspaceMsg([]uint64{2}), datatypeMsg(1, 8), contiguousLayoutMsg(0, 16), dimScaleAttr(),
+	})))
+	b.writeBytes(buildObjectHeader([][]byte{
+		dataspaceMsg([]uint64{2}), datatypeMsg(1, 8), contiguousLayoutMsg(bDataAddr, 16), dimScaleAttr(),
+	}))
+	b.writeBytes(bData)
+
+	// Global heap collection holding the single object reference to "b",
+	// for v's DIMENSION_LIST attribute.
+	ghAddr := b.addr()
+	b.writeBytes(buildGlobalHeapCollection(bHeaderAddr))
+
+	// Variable "v": 1-D, NC_FLOAT, bound to "b" via DIMENSION_LIST (not
+	// "a", even though both have length 2 and "a" comes first).
+	vData := append(putLE32fl(1.5), putLE32fl(2.5)...)
+	dimListAttr := buildMessage(msgAttribute, buildAttribute("DIMENSION_LIST", 9, []uint64{1}, buildDimensionListValue(ghAddr, 1)))
+	vHeaderAddr := b.addr()
+	vDataAddr := vHeaderAddr + uint64(len(buildObjectHeader([][]byte{
+		dataspaceMsg([]uint64{2}), datatypeMsg(1, 4), contiguousLayoutMsg(0, 8), dimListAttr,
+	})))
+	b.writeBytes(buildObjectHeader([][]byte{
+		dataspaceMsg([]uint64{2}), datatypeMsg(1, 4), contiguousLayoutMsg(vDataAddr, 8), dimListAttr,
+	}))
+	b.writeBytes(vData)
+
+	// Variable "flag": 1-D NC_CHAR (HDF5 string class), bound to "a" by
+	// plain length matching (no DIMENSION_LIST).
+	flagData := []byte("xy")
+	flagHeaderAddr := b.addr()
+	flagDataAddr := flagHeaderAddr + uint64(len(buildObjectHeader([][]byte{
+		dataspaceMsg([]uint64{2}), datatypeMsg(3, 1), contiguousLayoutMsg(0, 2),
+	})))
+	b.writeBytes(buildObjectHeader([][]byte{
+		dataspaceMsg([]uint64{2}), datatypeMsg(3, 1), contiguousLayoutMsg(flagDataAddr, 2),
+	}))
+	b.writeBytes(flagData)
+
+	// Symbol table node linking all four names to their object headers.
+	snodAddr := b.addr()
+	b.writeBytes([]byte("SNOD"))
+	b.writeBytes([]byte{1, 0})
+	b.writeBytes(putLE16(4))
+	entry := func(name string, headerAddr uint64) []byte {
+		e := putLE64(nameOffsets[name])
+		e = append(e, putLE64(headerAddr)...)
+		e = append(e, make([]byte, 4+4+16)...) // cache type + reserved + scratch
+		return e
+	}
+	b.writeBytes(entry("a", aHeaderAddr))
+	b.writeBytes(entry("b", bHeaderAddr))
+	b.writeBytes(entry("v", vHeaderAddr))
+	b.writeBytes(entry("flag", flagHeaderAddr))
+
+	// Single-leaf group B-tree pointing at the symbol table node.
+	btreeAddr := b.addr()
+	b.writeBytes([]byte("TREE"))
+	b.writeBytes([]byte{0, 0})
+	b.writeBytes(putLE16(1))
+	b.writeBytes(make([]byte, 8)) // left sibling
+	b.writeBytes(make([]byte, 8)) // right sibling
+	b.writeBytes(make([]byte, 8)) // K0 (unused by this reader)
+	b.writeBytes(putLE64(snodAddr))
+	b.writeBytes(make([]byte, 8)) // K1 (unused by this reader)
+
+	// Root group object header: just a Symbol Table message.
+	rootHeaderAddr := b.addr()
+	symTableMsg := buildMessage(0x0011, append(putLE64(btreeAddr), putLE64(heapHeaderAddr)...))
+	b.writeBytes(buildObjectHeader([][]byte{symTableMsg}))
+
+	out := b.buf.Bytes()
+
+	// Patch the superblock's root symbol table entry (link name offset 0 —
+	// the root entry has no name of its own — then the root object header
+	// address) and the EOF address.
+	copy(out[rootEntryOff+8:rootEntryOff+16], putLE64(rootHeaderAddr))
+	copy(out[eofOff:eofOff+8], putLE64(uint64(len(out))))
+
+	return out
+}
+
+func putLE64fl(f float64) []byte {
+	return putLE64(math.Float64bits(f))
+}
+
+func putLE32fl(f float32) []byte {
+	return putLE32(math.Float32bits(f))
+}
+
+func TestParseArgoLikeFixture(t *testing.T) {
+	data := buildArgoLikeFixture(t)
+	r := bytes.NewReader(data)
+
+	g, err := Parse(r, int64(len(data)))
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+
+	if len(g.Dims) != 2 {
+		t.Fatalf("got %d dims, want 2: %+v", len(g.Dims), g.Dims)
+	}
+	dimNames := map[string]uint64{}
+	for _, d := range g.Dims {
+		dimNames[d.Name] = d.Length
+	}
+	if dimNames["a"] != 2 || dimNames["b"] != 2 {
+		t.Fatalf("unexpected dims: %+v", g.Dims)
+	}
+
+	if len(g.Datasets) != 2 {
+		t.Fatalf("got %d datasets, want 2: %+v", len(g.Datasets), g.Datasets)
+	}
+
+	var v, flag *Dataset
+	for i := range g.Datasets {
+		switch g.Datasets[i].Name {
+		case "v":
+			v = &g.Datasets[i]
+		case "flag":
+			flag = &g.Datasets[i]
+		}
+	}
+	if v == nil || flag == nil {
+		t.Fatalf("missing expected datasets: %+v", g.Datasets)
+	}
+
+	if v.DataType != ncFloat {
+		t.Fatalf("v.DataType = %d, want NC_FLOAT", v.DataType)
+	}
+	if len(v.DimAddrs) != 1 {
+		t.Fatalf("v.DimAddrs = %+v, want 1 entry", v.DimAddrs)
+	}
+	var bAddr uint64
+	for _, d := range g.Dims {
+		if d.Name == "b" {
+			bAddr = d.Addr
+		}
+	}
+	if v.DimAddrs[0] != bAddr {
+		t.Fatalf("v is bound to dim addr %#x, want b's addr %#x (DIMENSION_LIST not honored)", v.DimAddrs[0], bAddr)
+	}
+
+	if flag.DataType != ncChar {
+		t.Fatalf("flag.DataType = %d, want NC_CHAR", flag.DataType)
+	}
+}
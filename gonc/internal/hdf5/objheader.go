@@ -0,0 +1,359 @@
+package hdf5
+
+import "fmt"
+
+// HDF5 object header message types this package understands. Anything
+// else is skipped.
+const (
+	msgNIL            = 0x0000
+	msgDataspace      = 0x0001
+	msgDatatype       = 0x0003
+	msgDataLayout     = 0x0008
+	msgFilterPipeline = 0x000B
+	msgAttribute      = 0x000C
+	msgContinuation   = 0x0010
+	msgSymbolTable    = 0x0011
+)
+
+type message struct {
+	msgType uint16
+	data    []byte
+}
+
+// readObjectHeader reads a version-1 object header (the format HDF5 1.8
+// writes by default) at addr and returns its messages, following
+// continuation blocks as needed.
+func (rd *reader) readObjectHeader(addr int64) ([]message, error) {
+	prefix, err := rd.readAt(addr, 16)
+	if err != nil {
+		return nil, err
+	}
+	if prefix[0] != 1 {
+		return nil, fmt.Errorf("hdf5: object header version %d not supported (only version 1 is)", prefix[0])
+	}
+
+	numMsgs := int(beUint(prefix[2:4]))
+	headerSize := int64(beUint(prefix[8:12]))
+
+	var msgs []message
+	pos := addr + 16
+	remaining := headerSize
+	read := 0
+
+	for remaining > 0 && read < numMsgs {
+		m, consumed, err := rd.readMessageHeader(pos)
+		if err != nil {
+			return nil, err
+		}
+		pos += consumed
+		remaining -= consumed
+		read++
+
+		if m.msgType == msgContinuation {
+			if len(m.data) < rd.sz.offset+rd.sz.length {
+				return nil, fmt.Errorf("hdf5: truncated continuation message")
+			}
+			contAddr := beUint(m.data[:rd.sz.offset])
+			contLen := beUint(m.data[rd.sz.offset : rd.sz.offset+rd.sz.length])
+			contMsgs, err := rd.readContinuationBlock(int64(contAddr), int64(contLen))
+			if err != nil {
+				return nil, err
+			}
+			msgs = append(msgs, contMsgs...)
+			continue
+		}
+
+		msgs = append(msgs, m)
+	}
+
+	return msgs, nil
+}
+
+// readContinuationBlock reads messages out of a raw continuation block: no
+// prefix of its own in object header version 1, just back-to-back
+// messages filling exactly length bytes.
+func (rd *reader) readContinuationBlock(addr, length int64) ([]message, error) {
+	var msgs []message
+	pos := addr
+	end := addr + length
+
+	for pos < end {
+		m, consumed, err := rd.readMessageHeader(pos)
+		if err != nil {
+			return nil, err
+		}
+		pos += consumed
+
+		if m.msgType == msgContinuation {
+			if len(m.data) < rd.sz.offset+rd.sz.length {
+				return nil, fmt.Errorf("hdf5: truncated continuation message")
+			}
+			contAddr := beUint(m.data[:rd.sz.offset])
+			contLen := beUint(m.data[rd.sz.offset : rd.sz.offset+rd.sz.length])
+			nested, err := rd.readContinuationBlock(int64(contAddr), int64(contLen))
+			if err != nil {
+				return nil, err
+			}
+			msgs = append(msgs, nested...)
+			continue
+		}
+
+		msgs = append(msgs, m)
+	}
+
+	return msgs, nil
+}
+
+// readMessageHeader reads one message at pos: type (2), size (2), flags
+// (1), 3 reserved bytes, then size bytes of data. It returns the message
+// and the number of bytes consumed (header + data).
+func (rd *reader) readMessageHeader(pos int64) (message, int64, error) {
+	hdr, err := rd.readAt(pos, 8)
+	if err != nil {
+		return message{}, 0, err
+	}
+	msgType := uint16(beUint(hdr[0:2]))
+	size := int64(beUint(hdr[2:4]))
+
+	data, err := rd.readAt(pos+8, int(size))
+	if err != nil {
+		return message{}, 0, err
+	}
+
+	return message{msgType: msgType, data: data}, 8 + size, nil
+}
+
+// parseDataspace reads a version-1 Dataspace message and returns the
+// dimension sizes.
+func parseDataspace(data []byte, sz sizes) ([]uint64, error) {
+	if len(data) < 8 {
+		return nil, fmt.Errorf("hdf5: truncated dataspace message")
+	}
+	version := data[0]
+	rank := int(data[1])
+	if version != 1 {
+		return nil, fmt.Errorf("hdf5: dataspace version %d not supported (only version 1 is)", version)
+	}
+
+	pos := 8
+	dims := make([]uint64, rank)
+	for i := 0; i < rank; i++ {
+		if pos+sz.length > len(data) {
+			return nil, fmt.Errorf("hdf5: truncated dataspace dimensions")
+		}
+		dims[i] = beUint(data[pos : pos+sz.length])
+		pos += sz.length
+	}
+
+	return dims, nil
+}
+
+// parseDatatype reads an HDF5 Datatype message and maps it to the closest
+// gonc NC_* external type: fixed-point -> NC_BYTE/NC_SHORT/NC_INT,
+// floating-point -> NC_FLOAT/NC_DOUBLE (keyed off byte size), and the
+// HDF5 string class netCDF-4 uses to store NC_CHAR -> NC_CHAR.
+func parseDatatype(data []byte) (uint32, error) {
+	if len(data) < 8 {
+		return 0, fmt.Errorf("hdf5: truncated datatype message")
+	}
+	class := data[0] & 0x0F
+	size := beUint(data[4:8])
+
+	switch class {
+	case 0: // fixed-point
+		switch size {
+		case 1:
+			return ncByte, nil
+		case 2:
+			return ncShort, nil
+		case 4:
+			return ncInt, nil
+		default:
+			return 0, fmt.Errorf("hdf5: unsupported integer size %d", size)
+		}
+	case 1: // floating-point
+		switch size {
+		case 4:
+			return ncFloat, nil
+		case 8:
+			return ncDouble, nil
+		default:
+			return 0, fmt.Errorf("hdf5: unsupported float size %d", size)
+		}
+	case 3: // string; netCDF-4 stores NC_CHAR variables this way
+		return ncChar, nil
+	default:
+		return 0, fmt.Errorf("hdf5: unsupported datatype class %d", class)
+	}
+}
+
+// dataLayout is the parsed form of a version-3 Data Layout message.
+type dataLayout struct {
+	class byte // 1 = contiguous, 2 = chunked, 0 = compact
+	addr  uint64
+	size  uint64
+
+	// btreeAddr and chunkDims are populated for class 2 (chunked) only.
+	// chunkDims has one entry per dataspace dimension plus a trailing
+	// "dataset element size" dimension, exactly as HDF5 stores it.
+	btreeAddr uint64
+	chunkDims []uint64
+}
+
+// parseDataLayout reads a Data Layout message. Contiguous and chunked
+// layouts are both supported; compact layouts are reported via class 0
+// with no address, since a caller may still want the dataset's metadata.
+func parseDataLayout(data []byte, sz sizes) (dataLayout, error) {
+	if len(data) < 2 {
+		return dataLayout{}, fmt.Errorf("hdf5: truncated data layout message")
+	}
+	version := data[0]
+	if version != 3 {
+		return dataLayout{}, fmt.Errorf("hdf5: data layout version %d not supported (only version 3 is)", version)
+	}
+
+	class := data[1]
+	switch class {
+	case 1: // contiguous
+		if len(data) < 2+sz.offset+sz.length {
+			return dataLayout{}, fmt.Errorf("hdf5: truncated contiguous layout message")
+		}
+		addr := beUint(data[2 : 2+sz.offset])
+		size := beUint(data[2+sz.offset : 2+sz.offset+sz.length])
+		return dataLayout{class: 1, addr: addr, size: size}, nil
+	case 2: // chunked
+		if len(data) < 3 {
+			return dataLayout{}, fmt.Errorf("hdf5: truncated chunked layout message")
+		}
+		rank := int(data[2])
+		pos := 3
+		if len(data) < pos+sz.offset {
+			return dataLayout{}, fmt.Errorf("hdf5: truncated chunked layout message")
+		}
+		btreeAddr := beUint(data[pos : pos+sz.offset])
+		pos += sz.offset
+
+		dims := make([]uint64, rank)
+		for i := 0; i < rank; i++ {
+			if pos+4 > len(data) {
+				return dataLayout{}, fmt.Errorf("hdf5: truncated chunked layout dimensions")
+			}
+			dims[i] = beUint(data[pos : pos+4])
+			pos += 4
+		}
+		return dataLayout{class: 2, btreeAddr: btreeAddr, chunkDims: dims}, nil
+	default: // compact
+		return dataLayout{class: class}, nil
+	}
+}
+
+// parseFilterPipeline reads a version-1 or version-2 Filter Pipeline
+// message and returns the registered filter ID of each filter in the
+// pipeline, in application order.
+func parseFilterPipeline(data []byte) ([]uint16, error) {
+	if len(data) < 2 {
+		return nil, fmt.Errorf("hdf5: truncated filter pipeline message")
+	}
+	version := data[0]
+	n := int(data[1])
+
+	var pos int
+	switch version {
+	case 1:
+		pos = 8 // version(1) + nfilters(1) + 6 reserved bytes
+	case 2:
+		pos = 2
+	default:
+		return nil, fmt.Errorf("hdf5: filter pipeline version %d not supported", version)
+	}
+
+	ids := make([]uint16, 0, n)
+	for i := 0; i < n; i++ {
+		if pos+2 > len(data) {
+			return nil, fmt.Errorf("hdf5: truncated filter pipeline entry")
+		}
+		id := uint16(beUint(data[pos : pos+2]))
+		ids = append(ids, id)
+		pos += 2
+
+		// Version 2 omits the name entirely for the standard filters
+		// (ID < 256); version 1 always carries one.
+		hasName := version == 1 || id >= 256
+		nameLen := 0
+		if hasName {
+			if pos+2 > len(data) {
+				return nil, fmt.Errorf("hdf5: truncated filter pipeline entry")
+			}
+			nameLen = int(beUint(data[pos : pos+2]))
+			pos += 2
+		}
+
+		if pos+4 > len(data) {
+			return nil, fmt.Errorf("hdf5: truncated filter pipeline entry")
+		}
+		pos += 2 // flags
+		numValues := int(beUint(data[pos : pos+2]))
+		pos += 2
+
+		if hasName {
+			pos += pad8(nameLen)
+		}
+		pos += numValues * 4
+		if version == 1 && numValues%2 != 0 {
+			pos += 4 // version 1 pads client data to a multiple of 8 bytes
+		}
+	}
+	return ids, nil
+}
+
+// parseAttribute reads a version-1 Attribute message: name, datatype and
+// dataspace sub-messages (each padded to an 8-byte boundary), followed by
+// the raw attribute data.
+func parseAttribute(data []byte) (Attr, error) {
+	if len(data) < 8 {
+		return Attr{}, fmt.Errorf("hdf5: truncated attribute message")
+	}
+	nameSize := int(beUint(data[2:4]))
+	dtSize := int(beUint(data[4:6]))
+	dsSize := int(beUint(data[6:8]))
+
+	pos := 8
+	if pos+nameSize > len(data) {
+		return Attr{}, fmt.Errorf("hdf5: truncated attribute name")
+	}
+	name := cString(data[pos : pos+nameSize])
+	pos += pad8(nameSize)
+
+	if pos+dtSize > len(data) {
+		return Attr{}, fmt.Errorf("hdf5: truncated attribute datatype")
+	}
+	dtype, err := parseDatatype(data[pos : pos+dtSize])
+	if err != nil {
+		// Attributes whose datatype this package doesn't model (e.g. HDF5
+		// string/reference types used for things like DIMENSION_LIST)
+		// still carry useful raw bytes; keep them with a zero type rather
+		// than failing the whole dataset.
+		dtype = 0
+	}
+	pos += pad8(dtSize)
+
+	if pos+dsSize > len(data) {
+		return Attr{}, fmt.Errorf("hdf5: truncated attribute dataspace")
+	}
+	pos += pad8(dsSize)
+
+	return Attr{Name: name, Type: dtype, Values: append([]byte(nil), data[pos:]...)}, nil
+}
+
+func pad8(n int) int {
+	return (n + 7) &^ 7
+}
+
+func cString(b []byte) string {
+	for i, c := range b {
+		if c == 0 {
+			return string(b[:i])
+		}
+	}
+	return string(b)
+}
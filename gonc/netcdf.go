@@ -1,304 +1,489 @@
-package gonc
-
-import (
-	"encoding/binary"
-	"errors"
-	"fmt"
-	"os"
-)
-
-const (
-	ClassicFormat     = 1
-	Format64BitOffset = 2
-)
-
-type File struct {
-	f       *os.File
-	Format  byte
-	NumRecs uint32
-	Dims    []Dimension
-	Vars    []Variable
-}
-
-type Dimension struct {
-	Name   string
-	Length uint32
-}
-
-type Variable struct {
-	Name     string
-	DimIDs   []uint32
-	DataType uint32
-	VSize    uint32
-	Offset   uint32
-	Attrs    []Attribute
-}
-
-type Attribute struct {
-	Name   string
-	Type   uint32
-	Values []byte
-}
-
-func Open(path string) (*File, error) {
-	f, err := os.Open(path)
-	if err != nil {
-		return nil, err
-	}
-
-	header := make([]byte, 4)
-	_, err = f.Read(header)
-	if err != nil {
-		f.Close()
-		return nil, err
-	}
-
-	if string(header[:3]) != "CDF" {
-		f.Close()
-		return nil, errors.New("not a NetCDF file")
-	}
-
-	format := header[3]
-	if format != ClassicFormat && format != Format64BitOffset {
-		f.Close()
-		return nil, fmt.Errorf("unsupported NetCDF format: %d", format)
-	}
-
-	buf := make([]byte, 4)
-	_, err = f.Read(buf)
-	if err != nil {
-		f.Close()
-		return nil, err
-	}
-	numrecs := binary.BigEndian.Uint32(buf)
-
-	nc := &File{
-		f:       f,
-		Format:  format,
-		NumRecs: numrecs,
-		Dims:    []Dimension{},
-		Vars:    []Variable{},
-	}
-
-	if err := nc.readDimList(); err != nil {
-		f.Close()
-		return nil, err
-	}
-
-	if err := nc.readVarList(); err != nil {
-		f.Close()
-		return nil, err
-	}
-
-	return nc, nil
-}
-
-func readU32(f *os.File) (uint32, error) {
-	buf := make([]byte, 4)
-	_, err := f.Read(buf)
-	if err != nil {
-		return 0, err
-	}
-	return binary.BigEndian.Uint32(buf), nil
-}
-
-func readString(f *os.File) (string, error) {
-	n, err := readU32(f)
-	if err != nil {
-		return "", err
-	}
-
-	buf := make([]byte, n)
-	_, err = f.Read(buf)
-	if err != nil {
-		return "", err
-	}
-
-	pad := (4 - (n % 4)) % 4
-	if pad > 0 {
-		_, err = f.Seek(int64(pad), 1)
-		if err != nil {
-			return "", err
-		}
-	}
-
-	return string(buf), nil
-}
-
-func (nc *File) Close() error {
-	return nc.f.Close()
-}
-
-func (nc *File) readDimList() error {
-	tag, err := readU32(nc.f)
-	if err != nil {
-		return err
-	}
-
-	if tag == 0 {
-		nc.Dims = []Dimension{}
-		return nil
-	}
-
-	if tag != 0x0A {
-		return fmt.Errorf("invalid dim_list tag: %d", tag)
-	}
-
-	nelems, err := readU32(nc.f)
-	if err != nil {
-		return err
-	}
-
-	dims := make([]Dimension, 0, nelems)
-
-	for i := 0; i < int(nelems); i++ {
-		name, err := readString(nc.f)
-		if err != nil {
-			return err
-		}
-
-		length, err := readU32(nc.f)
-		if err != nil {
-			return err
-		}
-
-		dims = append(dims, Dimension{Name: name, Length: length})
-	}
-
-	nc.Dims = dims
-	return nil
-}
-
-func (nc *File) readAttrList() ([]Attribute, error) {
-	tag, err := readU32(nc.f)
-	if err != nil {
-		return nil, err
-	}
-
-	if tag == 0 {
-		return []Attribute{}, nil
-	}
-
-	if tag != 0x0C {
-		return nil, fmt.Errorf("invalid attr_list tag: %d", tag)
-	}
-
-	nelems, err := readU32(nc.f)
-	if err != nil {
-		return nil, err
-	}
-
-	attrs := make([]Attribute, 0, nelems)
-
-	for i := 0; i < int(nelems); i++ {
-		name, err := readString(nc.f)
-		if err != nil {
-			return nil, err
-		}
-
-		atype, err := readU32(nc.f)
-		if err != nil {
-			return nil, err
-		}
-
-		nvals, err := readU32(nc.f)
-		if err != nil {
-			return nil, err
-		}
-
-		buf := make([]byte, nvals)
-		_, err = nc.f.Read(buf)
-		if err != nil {
-			return nil, err
-		}
-
-		pad := (4 - (nvals % 4)) % 4
-		if pad > 0 {
-			nc.f.Seek(int64(pad), 1)
-		}
-
-		attrs = append(attrs, Attribute{
-			Name:   name,
-			Type:   atype,
-			Values: buf,
-		})
-	}
-
-	return attrs, nil
-}
-
-func (nc *File) readVarList() error {
-	tag, err := readU32(nc.f)
-	if err != nil {
-		return err
-	}
-
-	if tag == 0 {
-		nc.Vars = []Variable{}
-		return nil
-	}
-
-	if tag != 0x0B {
-		return fmt.Errorf("invalid var_list tag: %d", tag)
-	}
-
-	nelems, err := readU32(nc.f)
-	if err != nil {
-		return err
-	}
-
-	vars := make([]Variable, 0, nelems)
-
-	for i := 0; i < int(nelems); i++ {
-
-		name, err := readString(nc.f)
-		if err != nil {
-			return err
-		}
-
-		nDims, err := readU32(nc.f)
-		if err != nil {
-			return err
-		}
-
-		dimIDs := make([]uint32, nDims)
-		for j := uint32(0); j < nDims; j++ {
-			dimIDs[j], err = readU32(nc.f)
-			if err != nil {
-				return err
-			}
-		}
-
-		attrs, err := nc.readAttrList()
-		if err != nil {
-			return err
-		}
-
-		dtype, err := readU32(nc.f)
-		if err != nil {
-			return err
-		}
-
-		vsize, err := readU32(nc.f)
-		if err != nil {
-			return err
-		}
-
-		offset, err := readU32(nc.f)
-		if err != nil {
-			return err
-		}
-
-		vars = append(vars, Variable{
-			Name:     name,
-			DimIDs:   dimIDs,
-			Attrs:    attrs,
-			DataType: dtype,
-			VSize:    vsize,
-			Offset:   offset,
-		})
-	}
-
-	nc.Vars = vars
-	return nil
-}
+package gonc
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+)
+
+const (
+	ClassicFormat     = 1
+	Format64BitOffset = 2
+	NetCDF4Format     = 3 // HDF5-backed (the netCDF-4 / netCDF-4 classic model)
+	CDF5Format        = 5 // CDF-5, a.k.a. NC_64BIT_DATA
+)
+
+// hdf5Signature is the 8-byte superblock magic that marks a netCDF-4 file,
+// which is really an HDF5 container underneath.
+var hdf5Signature = []byte{0x89, 'H', 'D', 'F', '\r', '\n', 0x1a, '\n'}
+
+type File struct {
+	r      io.ReaderAt
+	closer io.Closer
+	size   int64
+
+	Format  byte
+	NumRecs uint32
+	Dims    []Dimension
+	Attrs   []Attribute
+	Vars    []Variable
+}
+
+type Dimension struct {
+	Name   string
+	Length uint32
+
+	// Length64 is Length widened to 64 bits. It is always populated,
+	// including for classic CDF-1/CDF-2 files, so callers that need to
+	// handle CDF-5's 64-bit dimension lengths don't have to special-case
+	// the format. For classic files Length64 == uint64(Length).
+	Length64 uint64
+}
+
+type Variable struct {
+	Name     string
+	DimIDs   []uint32
+	DataType uint32
+	VSize    uint32
+	Offset   uint32
+	Attrs    []Attribute
+
+	// VSize64 and Offset64 widen VSize and Offset to 64 bits, always
+	// populated. CDF-5 and netCDF-4 files can exceed the 4GiB range the
+	// uint32 fields support; classic files simply mirror them.
+	VSize64  uint64
+	Offset64 uint64
+
+	// Codec, if non-nil, decodes each of v's storage chunks before Read
+	// assembles the hyperslab. It is populated automatically from the HDF5
+	// filter pipeline message of a chunked netCDF-4 variable. Read applies
+	// it to chunked variables only; contiguously stored data (every classic
+	// and CDF-5 variable) is read as is, and no _DeflateLevel attribute is
+	// examined.
+	Codec Codec
+
+	// ChunkShape is the shape of one storage chunk, in elements, for
+	// chunked (netCDF-4/HDF5) variables. It is nil for variables stored
+	// contiguously, which is always true for classic and CDF-5 files.
+	ChunkShape []uint32
+
+	chunks             []chunkLoc
+	unsupportedFilters []uint16
+
+	nc *File
+}
+
+type Attribute struct {
+	Name   string
+	Type   uint32
+	Values []byte
+}
+
+// Open opens the NetCDF file at path and parses its header.
+func Open(path string) (*File, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+
+	nc, err := OpenReaderAt(f, info.Size())
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+
+	nc.closer = f
+	return nc, nil
+}
+
+// OpenReaderAt parses a NetCDF header from r, which holds size bytes of
+// CDF-1, CDF-2, CDF-5 or netCDF-4 (HDF5) data; the format is detected from
+// the leading magic bytes. Unlike Open, the caller retains ownership of r:
+// if it needs closing (a file, for instance), call Close on it directly,
+// or wrap it so Close reaches it. Because reads go through io.ReaderAt, a
+// *File backed by OpenReaderAt may be shared across goroutines that decode
+// different variables concurrently.
+func OpenReaderAt(r io.ReaderAt, size int64) (*File, error) {
+	magic := make([]byte, 8)
+	n, err := r.ReadAt(magic, 0)
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+	magic = magic[:n]
+
+	if len(magic) >= len(hdf5Signature) && bytes.Equal(magic[:len(hdf5Signature)], hdf5Signature) {
+		return openNetCDF4(r, size)
+	}
+
+	if len(magic) < 4 || string(magic[:3]) != "CDF" {
+		return nil, errors.New("not a NetCDF file")
+	}
+
+	switch format := magic[3]; format {
+	case ClassicFormat, Format64BitOffset:
+		return openClassic(r, size, format)
+	case CDF5Format:
+		return openCDF5(r, size)
+	default:
+		return nil, fmt.Errorf("unsupported NetCDF format: %d", format)
+	}
+}
+
+func openClassic(r io.ReaderAt, size int64, format byte) (*File, error) {
+	hr := io.NewSectionReader(r, 0, size)
+	if _, err := hr.Seek(4, io.SeekStart); err != nil {
+		return nil, err
+	}
+
+	buf := make([]byte, 4)
+	if _, err := io.ReadFull(hr, buf); err != nil {
+		return nil, err
+	}
+	numrecs := binary.BigEndian.Uint32(buf)
+
+	nc := &File{
+		r:       r,
+		size:    size,
+		Format:  format,
+		NumRecs: numrecs,
+		Dims:    []Dimension{},
+		Vars:    []Variable{},
+	}
+
+	if err := nc.readDimList(hr); err != nil {
+		return nil, err
+	}
+
+	attrs, err := nc.readAttrList(hr)
+	if err != nil {
+		return nil, err
+	}
+	nc.Attrs = attrs
+
+	if err := nc.readVarList(hr); err != nil {
+		return nil, err
+	}
+
+	return nc, nil
+}
+
+func readU32(r io.Reader) (uint32, error) {
+	buf := make([]byte, 4)
+	_, err := io.ReadFull(r, buf)
+	if err != nil {
+		return 0, err
+	}
+	return binary.BigEndian.Uint32(buf), nil
+}
+
+// remaining returns how many bytes are left unread in r, so a length
+// field read from untrusted header bytes can be checked against it
+// before it is used as an allocation size.
+func remaining(r *io.SectionReader) (int64, error) {
+	pos, err := r.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return 0, err
+	}
+	return r.Size() - pos, nil
+}
+
+func readString(r *io.SectionReader) (string, error) {
+	n, err := readU32(r)
+	if err != nil {
+		return "", err
+	}
+
+	rem, err := remaining(r)
+	if err != nil {
+		return "", err
+	}
+	if int64(n) > rem {
+		return "", fmt.Errorf("gonc: string length %d exceeds %d remaining header bytes", n, rem)
+	}
+
+	buf := make([]byte, n)
+	_, err = io.ReadFull(r, buf)
+	if err != nil {
+		return "", err
+	}
+
+	pad := (4 - (n % 4)) % 4
+	if pad > 0 {
+		if _, err := io.ReadFull(r, make([]byte, pad)); err != nil {
+			return "", err
+		}
+	}
+
+	return string(buf), nil
+}
+
+// Close releases the resources backing nc. It is a no-op if nc was opened
+// with OpenReaderAt, since the caller owns the underlying reader in that
+// case.
+func (nc *File) Close() error {
+	if nc.closer == nil {
+		return nil
+	}
+	return nc.closer.Close()
+}
+
+// minDimSize is the fewest bytes a single dim_list entry can occupy: a
+// zero-length name (4 bytes) plus its 4-byte length field.
+const minDimSize = 8
+
+func (nc *File) readDimList(r *io.SectionReader) error {
+	tag, err := readU32(r)
+	if err != nil {
+		return err
+	}
+
+	if tag == 0 {
+		nc.Dims = []Dimension{}
+		return nil
+	}
+
+	if tag != 0x0A {
+		return fmt.Errorf("invalid dim_list tag: %d", tag)
+	}
+
+	nelems, err := readU32(r)
+	if err != nil {
+		return err
+	}
+
+	rem, err := remaining(r)
+	if err != nil {
+		return err
+	}
+	if int64(nelems) > rem/minDimSize {
+		return fmt.Errorf("gonc: dim_list claims %d entries, but only %d header bytes remain", nelems, rem)
+	}
+
+	dims := make([]Dimension, 0, nelems)
+
+	for i := 0; i < int(nelems); i++ {
+		name, err := readString(r)
+		if err != nil {
+			return err
+		}
+
+		length, err := readU32(r)
+		if err != nil {
+			return err
+		}
+
+		dims = append(dims, Dimension{Name: name, Length: length, Length64: uint64(length)})
+	}
+
+	nc.Dims = dims
+	return nil
+}
+
+// minAttrSize is the fewest bytes a single attr_list entry can occupy: a
+// zero-length name (4 bytes), a 4-byte type, and a 4-byte value count.
+const minAttrSize = 12
+
+func (nc *File) readAttrList(r *io.SectionReader) ([]Attribute, error) {
+	tag, err := readU32(r)
+	if err != nil {
+		return nil, err
+	}
+
+	if tag == 0 {
+		return []Attribute{}, nil
+	}
+
+	if tag != 0x0C {
+		return nil, fmt.Errorf("invalid attr_list tag: %d", tag)
+	}
+
+	nelems, err := readU32(r)
+	if err != nil {
+		return nil, err
+	}
+
+	rem, err := remaining(r)
+	if err != nil {
+		return nil, err
+	}
+	if int64(nelems) > rem/minAttrSize {
+		return nil, fmt.Errorf("gonc: attr_list claims %d entries, but only %d header bytes remain", nelems, rem)
+	}
+
+	attrs := make([]Attribute, 0, nelems)
+
+	for i := 0; i < int(nelems); i++ {
+		name, err := readString(r)
+		if err != nil {
+			return nil, err
+		}
+
+		atype, err := readU32(r)
+		if err != nil {
+			return nil, err
+		}
+
+		nvals, err := readU32(r)
+		if err != nil {
+			return nil, err
+		}
+
+		rem, err := remaining(r)
+		if err != nil {
+			return nil, err
+		}
+		if int64(nvals) > rem {
+			return nil, fmt.Errorf("gonc: attribute %q values length %d exceeds %d remaining header bytes", name, nvals, rem)
+		}
+
+		buf := make([]byte, nvals)
+		_, err = io.ReadFull(r, buf)
+		if err != nil {
+			return nil, err
+		}
+
+		pad := (4 - (nvals % 4)) % 4
+		if pad > 0 {
+			if _, err := io.ReadFull(r, make([]byte, pad)); err != nil {
+				return nil, err
+			}
+		}
+
+		attrs = append(attrs, Attribute{
+			Name:   name,
+			Type:   atype,
+			Values: buf,
+		})
+	}
+
+	return attrs, nil
+}
+
+// minVarSize is the fewest bytes a single var_list entry can occupy: a
+// zero-length name (4), a zero dim count (4), an empty attr_list tag (4),
+// a 4-byte type, a 4-byte vsize and a 4-byte offset.
+const minVarSize = 24
+
+func (nc *File) readVarList(r *io.SectionReader) error {
+	tag, err := readU32(r)
+	if err != nil {
+		return err
+	}
+
+	if tag == 0 {
+		nc.Vars = []Variable{}
+		return nil
+	}
+
+	if tag != 0x0B {
+		return fmt.Errorf("invalid var_list tag: %d", tag)
+	}
+
+	nelems, err := readU32(r)
+	if err != nil {
+		return err
+	}
+
+	rem, err := remaining(r)
+	if err != nil {
+		return err
+	}
+	if int64(nelems) > rem/minVarSize {
+		return fmt.Errorf("gonc: var_list claims %d entries, but only %d header bytes remain", nelems, rem)
+	}
+
+	vars := make([]Variable, 0, nelems)
+
+	for i := 0; i < int(nelems); i++ {
+
+		name, err := readString(r)
+		if err != nil {
+			return err
+		}
+
+		nDims, err := readU32(r)
+		if err != nil {
+			return err
+		}
+
+		remDims, err := remaining(r)
+		if err != nil {
+			return err
+		}
+		if int64(nDims) > remDims/4 {
+			return fmt.Errorf("gonc: variable %q claims %d dimensions, but only %d header bytes remain", name, nDims, remDims)
+		}
+
+		dimIDs := make([]uint32, nDims)
+		for j := uint32(0); j < nDims; j++ {
+			dimIDs[j], err = readU32(r)
+			if err != nil {
+				return err
+			}
+			if dimIDs[j] >= uint32(len(nc.Dims)) {
+				return fmt.Errorf("gonc: variable %q references dim id %d, but the file only has %d dims", name, dimIDs[j], len(nc.Dims))
+			}
+		}
+
+		attrs, err := nc.readAttrList(r)
+		if err != nil {
+			return err
+		}
+
+		dtype, err := readU32(r)
+		if err != nil {
+			return err
+		}
+
+		vsize, err := readU32(r)
+		if err != nil {
+			return err
+		}
+
+		// begin is 4 bytes in CDF-1 and 8 bytes in CDF-2.
+		var offset uint64
+		if nc.Format == Format64BitOffset {
+			offset, err = readU64(r)
+		} else {
+			var off32 uint32
+			off32, err = readU32(r)
+			offset = uint64(off32)
+		}
+		if err != nil {
+			return err
+		}
+
+		isRecord := len(dimIDs) > 0 && nc.Dims[dimIDs[0]].Length == 0
+		if !isRecord && int64(offset)+int64(vsize) > nc.size {
+			return fmt.Errorf("gonc: variable %q data [%d, %d) extends past the %d-byte file", name, offset, offset+uint64(vsize), nc.size)
+		}
+
+		v := Variable{
+			Name:     name,
+			DimIDs:   dimIDs,
+			Attrs:    attrs,
+			DataType: dtype,
+			VSize:    vsize,
+			Offset:   uint32(offset),
+			VSize64:  uint64(vsize),
+			Offset64: offset,
+		}
+		vars = append(vars, v)
+	}
+
+	for i := range vars {
+		vars[i].nc = nc
+	}
+
+	nc.Vars = vars
+	return nil
+}
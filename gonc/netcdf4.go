@@ -0,0 +1,123 @@
+package gonc
+
+import (
+	"fmt"
+	"io"
+
+	"floatchat-gopy/gonc/internal/hdf5"
+)
+
+// openNetCDF4 parses a netCDF-4 (HDF5-backed) file via the internal hdf5
+// package and maps its Dims/Datasets onto the same Dimension/Variable/
+// Attribute types the classic reader produces, so callers don't have to
+// branch on Format to use nc.Dims and nc.Vars.
+func openNetCDF4(r io.ReaderAt, size int64) (*File, error) {
+	g, err := hdf5.Parse(r, size)
+	if err != nil {
+		return nil, fmt.Errorf("gonc: netCDF-4 file: %w", err)
+	}
+
+	nc := &File{
+		r:      r,
+		size:   size,
+		Format: NetCDF4Format,
+		Dims:   make([]Dimension, 0, len(g.Dims)),
+		Vars:   make([]Variable, 0, len(g.Datasets)),
+	}
+
+	for _, a := range g.Attrs {
+		nc.Attrs = append(nc.Attrs, Attribute{Name: a.Name, Type: a.Type, Values: a.Values})
+	}
+
+	dimByAddr := make(map[uint64]int, len(g.Dims))
+	for _, d := range g.Dims {
+		dimByAddr[d.Addr] = len(nc.Dims)
+		nc.Dims = append(nc.Dims, Dimension{Name: d.Name, Length: uint32(d.Length), Length64: d.Length})
+	}
+
+	for _, ds := range g.Datasets {
+		dimIDs := make([]uint32, len(ds.Shape))
+		for i, length := range ds.Shape {
+			var addr uint64
+			if i < len(ds.DimAddrs) {
+				addr = ds.DimAddrs[i]
+			}
+			if id, ok := dimByAddr[addr]; addr != 0 && ok {
+				dimIDs[i] = uint32(id)
+			} else {
+				dimIDs[i] = uint32(nc.matchOrCreateDim(length))
+			}
+		}
+
+		attrs := make([]Attribute, 0, len(ds.Attrs))
+		for _, a := range ds.Attrs {
+			attrs = append(attrs, Attribute{Name: a.Name, Type: a.Type, Values: a.Values})
+		}
+
+		v := Variable{
+			Name:     ds.Name,
+			DimIDs:   dimIDs,
+			DataType: ds.DataType,
+			VSize:    uint32(ds.Size),
+			Offset:   uint32(ds.Offset),
+			VSize64:  ds.Size,
+			Offset64: ds.Offset,
+			Attrs:    attrs,
+		}
+		applyChunking(&v, ds)
+		nc.Vars = append(nc.Vars, v)
+	}
+
+	for i := range nc.Vars {
+		nc.Vars[i].nc = nc
+	}
+
+	return nc, nil
+}
+
+// applyChunking copies an HDF5 dataset's chunked-storage metadata onto v:
+// its chunk shape, chunk locations, and the codec (if any) its filter
+// pipeline resolves to. A filter present in the pipeline that doesn't
+// resolve to a registered codec is recorded on v so Read fails clearly
+// instead of silently handing back undecoded bytes.
+func applyChunking(v *Variable, ds hdf5.Dataset) {
+	if len(ds.ChunkShape) == 0 {
+		return
+	}
+
+	v.ChunkShape = make([]uint32, len(ds.ChunkShape))
+	for i, n := range ds.ChunkShape {
+		v.ChunkShape[i] = uint32(n)
+	}
+
+	v.chunks = make([]chunkLoc, len(ds.Chunks))
+	for i, c := range ds.Chunks {
+		v.chunks[i] = chunkLoc{coords: c.Coords, offset: c.Offset, size: c.Size}
+	}
+
+	for _, id := range ds.FilterIDs {
+		if c, ok := codecByID(id); ok {
+			v.Codec = c
+		} else {
+			v.unsupportedFilters = append(v.unsupportedFilters, id)
+		}
+	}
+}
+
+// matchOrCreateDim is the fallback axis-to-dimension binding used when a
+// dataset has no (or no resolvable) DIMENSION_LIST reference for an axis: it
+// finds the first dimension with the given length, or — as netCDF-C itself
+// does for netCDF-4 datasets whose axes have no matching dimension-scale
+// dataset — synthesizes an anonymous "phony_dim_N" one. It is a last resort
+// because length alone doesn't distinguish same-length dimensions.
+func (nc *File) matchOrCreateDim(length uint64) int {
+	for i, d := range nc.Dims {
+		if d.Length64 == length {
+			return i
+		}
+	}
+
+	name := fmt.Sprintf("phony_dim_%d", len(nc.Dims))
+	nc.Dims = append(nc.Dims, Dimension{Name: name, Length: uint32(length), Length64: length})
+	return len(nc.Dims) - 1
+}
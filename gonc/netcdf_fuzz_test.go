@@ -0,0 +1,31 @@
+package gonc
+
+import (
+	"bytes"
+	"testing"
+)
+
+// FuzzOpen exercises the header parser with arbitrary bytes. Open must
+// never panic or attempt an unbounded allocation, regardless of how
+// implausible the claimed dim/attr/var counts or lengths are — the
+// hardening pattern archive/tar and archive/zip use for untrusted
+// archives. Seed corpus lives in testdata/fuzz/FuzzOpen.
+func FuzzOpen(f *testing.F) {
+	f.Fuzz(func(t *testing.T, data []byte) {
+		nc, err := OpenReaderAt(bytes.NewReader(data), int64(len(data)))
+		if err != nil {
+			return
+		}
+		for i := range nc.Vars {
+			_, _ = nc.Vars[i].Read(make([]int, len(nc.Vars[i].DimIDs)), onesOfLen(len(nc.Vars[i].DimIDs)), nil)
+		}
+	})
+}
+
+func onesOfLen(n int) []int {
+	out := make([]int, n)
+	for i := range out {
+		out[i] = 1
+	}
+	return out
+}
@@ -0,0 +1,47 @@
+package gonc
+
+import (
+	"os"
+	"testing"
+)
+
+// A record variable's data region depends on the file's current record
+// count, not its vsize (which the classic format always sets to the full
+// per-record size regardless of how many records are actually present).
+// A freshly-defined record variable with zero records is valid and must
+// not be rejected by the offset+vsize bounds check.
+func TestOpenRecordVarZeroRecords(t *testing.T) {
+	path := t.TempDir() + "/record.nc"
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	wr, err := NewWriter(f, ClassicFormat)
+	if err != nil {
+		t.Fatal(err)
+	}
+	timeID, err := wr.DefineDim("time", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := wr.DefineVar("t", NC_DOUBLE, []DimID{timeID}); err != nil {
+		t.Fatal(err)
+	}
+	if err := wr.EndDef(); err != nil {
+		t.Fatal(err)
+	}
+
+	info, err := f.Stat()
+	if err != nil {
+		t.Fatal(err)
+	}
+	nc, err := OpenReaderAt(f, info.Size())
+	if err != nil {
+		t.Fatalf("Open rejected a valid record-variable file with zero records: %v", err)
+	}
+	if len(nc.Vars) != 1 || nc.Vars[0].Name != "t" {
+		t.Fatalf("unexpected vars: %+v", nc.Vars)
+	}
+}
@@ -0,0 +1,274 @@
+package gonc
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+)
+
+// NetCDF external data types, as used in the variable list and attribute
+// list headers (see the classic format spec).
+const (
+	NC_BYTE   = 1
+	NC_CHAR   = 2
+	NC_SHORT  = 3
+	NC_INT    = 4
+	NC_FLOAT  = 5
+	NC_DOUBLE = 6
+)
+
+// ncTypeSize returns the on-disk element size, in bytes, of a NetCDF
+// external type.
+func ncTypeSize(dtype uint32) (int, error) {
+	switch dtype {
+	case NC_BYTE, NC_CHAR:
+		return 1, nil
+	case NC_SHORT:
+		return 2, nil
+	case NC_INT, NC_FLOAT:
+		return 4, nil
+	case NC_DOUBLE:
+		return 8, nil
+	default:
+		return 0, fmt.Errorf("gonc: unknown data type %d", dtype)
+	}
+}
+
+// dimLen returns the length of dimension id, resolving the unlimited
+// (record) dimension to the file's current record count.
+func (nc *File) dimLen(id uint32) uint32 {
+	d := nc.Dims[id]
+	if d.Length == 0 {
+		return nc.NumRecs
+	}
+	return d.Length
+}
+
+// isRecordVar reports whether v's leading dimension is the unlimited
+// dimension, i.e. its data lives in the record region of the file.
+func (nc *File) isRecordVar(v *Variable) bool {
+	return len(v.DimIDs) > 0 && nc.Dims[v.DimIDs[0]].Length == 0
+}
+
+// recSize returns the number of bytes occupied by one record, i.e. the sum
+// of every record variable's per-record size (vsize already includes the
+// 4-byte padding applied when the header was written).
+func (nc *File) recSize() int64 {
+	var size int64
+	for i := range nc.Vars {
+		if nc.isRecordVar(&nc.Vars[i]) {
+			size += int64(nc.Vars[i].VSize64)
+		}
+	}
+	return size
+}
+
+// Read fetches a hyperslab of v's data: start[i] is the origin along
+// dimension i, count[i] is the number of elements to read, and stride[i]
+// is the step between elements (stride may be nil, meaning all ones).
+// Semantics mirror netCDF-C's nc_get_vars_* family. The returned value is
+// one of []int8, []byte, []int16, []int32, []float32 or []float64
+// depending on the variable's DataType.
+func (v *Variable) Read(start, count, stride []int) (interface{}, error) {
+	if v.nc == nil {
+		return nil, errors.New("gonc: variable is not bound to an open file")
+	}
+	nc := v.nc
+
+	ndims := len(v.DimIDs)
+	if len(start) != ndims {
+		return nil, fmt.Errorf("gonc: start has %d entries, variable %q has rank %d", len(start), v.Name, ndims)
+	}
+	if len(count) != ndims {
+		return nil, fmt.Errorf("gonc: count has %d entries, variable %q has rank %d", len(count), v.Name, ndims)
+	}
+	if stride == nil {
+		stride = make([]int, ndims)
+		for i := range stride {
+			stride[i] = 1
+		}
+	} else if len(stride) != ndims {
+		return nil, fmt.Errorf("gonc: stride has %d entries, variable %q has rank %d", len(stride), v.Name, ndims)
+	}
+
+	dimLens := make([]int, ndims)
+	for i, id := range v.DimIDs {
+		dimLens[i] = int(nc.dimLen(id))
+	}
+
+	total := 1
+	for i := 0; i < ndims; i++ {
+		if count[i] <= 0 {
+			return nil, fmt.Errorf("gonc: count[%d] must be positive", i)
+		}
+		if stride[i] <= 0 {
+			return nil, fmt.Errorf("gonc: stride[%d] must be positive", i)
+		}
+		last := start[i] + (count[i]-1)*stride[i]
+		if start[i] < 0 || last >= dimLens[i] {
+			return nil, fmt.Errorf("gonc: hyperslab exceeds dim %d (length %d)", i, dimLens[i])
+		}
+		total *= count[i]
+	}
+
+	if v.chunks != nil {
+		return v.readChunked(start, count, stride, dimLens)
+	}
+
+	elemSize, err := ncTypeSize(v.DataType)
+	if err != nil {
+		return nil, err
+	}
+
+	isRecord := nc.isRecordVar(v)
+	var recSize int64
+	if isRecord {
+		recSize = nc.recSize()
+	}
+
+	// Row-major element strides of the full (unsliced) variable shape.
+	// The record dimension, if present, is handled separately via recSize
+	// since records are not contiguous with each other in the file.
+	elemStrides := make([]int64, ndims)
+	acc := int64(1)
+	for i := ndims - 1; i >= 0; i-- {
+		if isRecord && i == 0 {
+			continue
+		}
+		elemStrides[i] = acc
+		acc *= int64(dimLens[i])
+	}
+
+	// Fixed-size variables are stored contiguously in [Offset, Offset+VSize),
+	// so a SectionReader bounds every read to that variable's own bytes and
+	// lets independent goroutines decode different variables without
+	// sharing a cursor. Record variables are interleaved with other record
+	// variables across the record region, so they're read directly off the
+	// file's ReaderAt using absolute offsets instead.
+	var sr *io.SectionReader
+	if !isRecord {
+		sr = io.NewSectionReader(nc.r, int64(v.Offset64), int64(v.VSize64))
+	}
+
+	raw := make([]byte, total*elemSize)
+	idx := make([]int, ndims)
+	for n := 0; n < total; n++ {
+		off := int64(v.Offset64)
+		if isRecord {
+			rec := start[0] + idx[0]*stride[0]
+			off += int64(rec) * recSize
+			for d := 1; d < ndims; d++ {
+				off += int64(start[d]+idx[d]*stride[d]) * elemStrides[d] * int64(elemSize)
+			}
+		} else {
+			for d := 0; d < ndims; d++ {
+				off += int64(start[d]+idx[d]*stride[d]) * elemStrides[d] * int64(elemSize)
+			}
+		}
+
+		dst := raw[n*elemSize : (n+1)*elemSize]
+		if isRecord {
+			if _, err := nc.r.ReadAt(dst, off); err != nil {
+				return nil, err
+			}
+		} else {
+			if _, err := sr.ReadAt(dst, off-int64(v.Offset64)); err != nil {
+				return nil, err
+			}
+		}
+
+		for d := ndims - 1; d >= 0; d-- {
+			idx[d]++
+			if idx[d] < count[d] {
+				break
+			}
+			idx[d] = 0
+		}
+	}
+
+	return decodeSlab(v.DataType, raw)
+}
+
+// decodeSlab interprets a run of raw big-endian bytes as the Go slice type
+// matching a NetCDF external type.
+func decodeSlab(dtype uint32, raw []byte) (interface{}, error) {
+	switch dtype {
+	case NC_BYTE:
+		out := make([]int8, len(raw))
+		for i, b := range raw {
+			out[i] = int8(b)
+		}
+		return out, nil
+	case NC_CHAR:
+		out := make([]byte, len(raw))
+		copy(out, raw)
+		return out, nil
+	case NC_SHORT:
+		out := make([]int16, len(raw)/2)
+		for i := range out {
+			out[i] = int16(binary.BigEndian.Uint16(raw[i*2:]))
+		}
+		return out, nil
+	case NC_INT:
+		out := make([]int32, len(raw)/4)
+		for i := range out {
+			out[i] = int32(binary.BigEndian.Uint32(raw[i*4:]))
+		}
+		return out, nil
+	case NC_FLOAT:
+		out := make([]float32, len(raw)/4)
+		for i := range out {
+			out[i] = math.Float32frombits(binary.BigEndian.Uint32(raw[i*4:]))
+		}
+		return out, nil
+	case NC_DOUBLE:
+		out := make([]float64, len(raw)/8)
+		for i := range out {
+			out[i] = math.Float64frombits(binary.BigEndian.Uint64(raw[i*8:]))
+		}
+		return out, nil
+	default:
+		return nil, fmt.Errorf("gonc: unknown data type %d", dtype)
+	}
+}
+
+// ReadFloat32 reads a hyperslab of an NC_FLOAT variable.
+func (v *Variable) ReadFloat32(start, count, stride []int) ([]float32, error) {
+	data, err := v.Read(start, count, stride)
+	if err != nil {
+		return nil, err
+	}
+	out, ok := data.([]float32)
+	if !ok {
+		return nil, fmt.Errorf("gonc: variable %q is not NC_FLOAT", v.Name)
+	}
+	return out, nil
+}
+
+// ReadFloat64 reads a hyperslab of an NC_DOUBLE variable.
+func (v *Variable) ReadFloat64(start, count, stride []int) ([]float64, error) {
+	data, err := v.Read(start, count, stride)
+	if err != nil {
+		return nil, err
+	}
+	out, ok := data.([]float64)
+	if !ok {
+		return nil, fmt.Errorf("gonc: variable %q is not NC_DOUBLE", v.Name)
+	}
+	return out, nil
+}
+
+// ReadInt16 reads a hyperslab of an NC_SHORT variable.
+func (v *Variable) ReadInt16(start, count, stride []int) ([]int16, error) {
+	data, err := v.Read(start, count, stride)
+	if err != nil {
+		return nil, err
+	}
+	out, ok := data.([]int16)
+	if !ok {
+		return nil, fmt.Errorf("gonc: variable %q is not NC_SHORT", v.Name)
+	}
+	return out, nil
+}
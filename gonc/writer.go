@@ -0,0 +1,603 @@
+package gonc
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+)
+
+// DimID identifies a dimension defined with Writer.DefineDim.
+type DimID uint32
+
+// VarID identifies a variable defined with Writer.DefineVar. Global
+// identifies the file itself as the target of PutAttr, for global
+// attributes.
+type VarID int32
+
+const Global VarID = -1
+
+type writerVar struct {
+	name   string
+	dtype  uint32
+	dimIDs []DimID
+	attrs  []Attribute
+
+	isRecord bool
+	vsize    uint32
+	begin    uint64
+}
+
+// Writer builds a classic-format (CDF-1 or CDF-2) NetCDF file, following
+// the same define-mode / data-mode state machine as the classic netCDF
+// API: dimensions, variables and attributes are declared while in define
+// mode, EndDef freezes the header and computes variable offsets, and
+// WriteVar fills in data afterwards.
+type Writer struct {
+	w      io.WriteSeeker
+	format byte
+
+	defineMode bool
+	numrecs    uint32
+
+	dims  []Dimension
+	attrs []Attribute
+	vars  []writerVar
+}
+
+// NewWriter creates a Writer that emits the given classic format
+// (ClassicFormat or Format64BitOffset) to w.
+func NewWriter(w io.WriteSeeker, format byte) (*Writer, error) {
+	if format != ClassicFormat && format != Format64BitOffset {
+		return nil, fmt.Errorf("gonc: unsupported write format: %d", format)
+	}
+	return &Writer{w: w, format: format, defineMode: true}, nil
+}
+
+// DefineDim declares a dimension. A length of 0 marks it as the unlimited
+// (record) dimension; at most one dimension may be unlimited.
+func (wr *Writer) DefineDim(name string, length uint32) (DimID, error) {
+	if !wr.defineMode {
+		return 0, errors.New("gonc: DefineDim called outside define mode")
+	}
+	if length == 0 {
+		for _, d := range wr.dims {
+			if d.Length == 0 {
+				return 0, errors.New("gonc: only one unlimited dimension is allowed")
+			}
+		}
+	}
+
+	id := DimID(len(wr.dims))
+	wr.dims = append(wr.dims, Dimension{Name: name, Length: length, Length64: uint64(length)})
+	return id, nil
+}
+
+// DefineVar declares a variable of the given NC_* type over dims. If the
+// unlimited dimension is used, it must be dims[0].
+func (wr *Writer) DefineVar(name string, dtype uint32, dims []DimID) (VarID, error) {
+	if !wr.defineMode {
+		return 0, errors.New("gonc: DefineVar called outside define mode")
+	}
+	if _, err := ncTypeSize(dtype); err != nil {
+		return 0, err
+	}
+	for i, id := range dims {
+		if int(id) >= len(wr.dims) {
+			return 0, fmt.Errorf("gonc: unknown dimension id %d", id)
+		}
+		if i > 0 && wr.dims[id].Length == 0 {
+			return 0, errors.New("gonc: the unlimited dimension may only appear first in a variable's dimension list")
+		}
+	}
+
+	isRecord := len(dims) > 0 && wr.dims[dims[0]].Length == 0
+
+	id := VarID(len(wr.vars))
+	wr.vars = append(wr.vars, writerVar{
+		name:     name,
+		dtype:    dtype,
+		dimIDs:   append([]DimID(nil), dims...),
+		isRecord: isRecord,
+	})
+	return id, nil
+}
+
+// PutAttr attaches an attribute to varID, or to the file itself when varID
+// is Global. values must be a string (or []byte, for NC_CHAR) or one of
+// []int8, []int16, []int32, []float32, []float64.
+func (wr *Writer) PutAttr(varID VarID, name string, values interface{}) error {
+	if !wr.defineMode {
+		return errors.New("gonc: PutAttr called outside define mode")
+	}
+
+	dtype, raw, err := encodeAttrValues(values)
+	if err != nil {
+		return err
+	}
+	attr := Attribute{Name: name, Type: dtype, Values: raw}
+
+	if varID == Global {
+		wr.attrs = append(wr.attrs, attr)
+		return nil
+	}
+	if int(varID) < 0 || int(varID) >= len(wr.vars) {
+		return fmt.Errorf("gonc: unknown variable id %d", varID)
+	}
+	wr.vars[varID].attrs = append(wr.vars[varID].attrs, attr)
+	return nil
+}
+
+// EndDef freezes the header: it computes each variable's vsize and begin
+// (fixed-size variables first, then record variables, matching the layout
+// netCDF-C itself produces) and writes the header to the start of w.
+// WriteVar may only be called afterwards.
+func (wr *Writer) EndDef() error {
+	if !wr.defineMode {
+		return errors.New("gonc: EndDef called outside define mode")
+	}
+
+	numRecordVars := 0
+	for i := range wr.vars {
+		if wr.vars[i].isRecord {
+			numRecordVars++
+		}
+	}
+
+	for i := range wr.vars {
+		v := &wr.vars[i]
+		elemSize, err := ncTypeSize(v.dtype)
+		if err != nil {
+			return err
+		}
+
+		n := 1
+		for i, id := range v.dimIDs {
+			if i == 0 && v.isRecord {
+				continue
+			}
+			n *= int(wr.dims[id].Length)
+		}
+
+		vsize := n * elemSize
+		// The classic format spec rounds every variable's vsize up to a
+		// multiple of 4, except a lone record variable: with nothing else
+		// in the record region to align, its vsize is left unpadded.
+		if !(v.isRecord && numRecordVars == 1) {
+			if pad := vsize % 4; pad != 0 {
+				vsize += 4 - pad
+			}
+		}
+		v.vsize = uint32(vsize)
+	}
+
+	var fixed, record []*writerVar
+	for i := range wr.vars {
+		if wr.vars[i].isRecord {
+			record = append(record, &wr.vars[i])
+		} else {
+			fixed = append(fixed, &wr.vars[i])
+		}
+	}
+
+	headerLen, err := wr.headerSize()
+	if err != nil {
+		return err
+	}
+	begin := uint64(headerLen)
+	if pad := begin % 4; pad != 0 {
+		begin += 4 - pad
+	}
+
+	for _, v := range fixed {
+		v.begin = begin
+		begin += uint64(v.vsize)
+	}
+	for _, v := range record {
+		v.begin = begin
+		begin += uint64(v.vsize)
+	}
+
+	if err := wr.writeHeader(); err != nil {
+		return err
+	}
+
+	// The classic format pads every fixed-size variable's data out to a
+	// 4-byte boundary, but WriteVar only ever writes a variable's exact
+	// content bytes. For every variable but the last, that padding gap
+	// gets zero-filled for free once a later WriteVar extends the file
+	// past it; the last fixed variable has nothing after it to do that,
+	// so grow the file to the full fixed-region size here.
+	if len(fixed) > 0 {
+		last := fixed[len(fixed)-1]
+		end := int64(last.begin + uint64(last.vsize))
+		if end > 0 {
+			if _, err := wr.w.Seek(end-1, io.SeekStart); err != nil {
+				return err
+			}
+			if _, err := wr.w.Write([]byte{0}); err != nil {
+				return err
+			}
+		}
+	}
+
+	wr.defineMode = false
+	return nil
+}
+
+func (wr *Writer) headerSize() (int, error) {
+	var buf bytes.Buffer
+	if err := wr.encodeHeader(&buf); err != nil {
+		return 0, err
+	}
+	return buf.Len(), nil
+}
+
+func (wr *Writer) writeHeader() error {
+	var buf bytes.Buffer
+	if err := wr.encodeHeader(&buf); err != nil {
+		return err
+	}
+	if _, err := wr.w.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	_, err := wr.w.Write(buf.Bytes())
+	return err
+}
+
+func (wr *Writer) encodeHeader(buf *bytes.Buffer) error {
+	buf.WriteString("CDF")
+	buf.WriteByte(wr.format)
+
+	if err := writeU32(buf, wr.numrecs); err != nil {
+		return err
+	}
+	if err := writeDimList(buf, wr.dims); err != nil {
+		return err
+	}
+	if err := writeAttrList(buf, wr.attrs); err != nil {
+		return err
+	}
+	return writeVarList(buf, wr.format, wr.vars)
+}
+
+// updateNumRecs rewrites just the header's numrecs field, which sits right
+// after the 4-byte magic, so a record variable's write can grow the
+// unlimited dimension without re-encoding the rest of the (fixed-size)
+// header.
+func (wr *Writer) updateNumRecs() error {
+	if _, err := wr.w.Seek(4, io.SeekStart); err != nil {
+		return err
+	}
+	return writeU32(wr.w, wr.numrecs)
+}
+
+func (wr *Writer) recSize() int64 {
+	var size int64
+	for _, v := range wr.vars {
+		if v.isRecord {
+			size += int64(v.vsize)
+		}
+	}
+	return size
+}
+
+// WriteVar writes a hyperslab of data to varID: start[i] is the origin
+// along dimension i and count[i] is the number of elements, in the same
+// row-major order as the slice passed in data. data must be the Go slice
+// type matching the variable's NC_* data type, e.g. []float64 for
+// NC_DOUBLE.
+func (wr *Writer) WriteVar(varID VarID, start, count []int, data interface{}) error {
+	if wr.defineMode {
+		return errors.New("gonc: WriteVar called while still in define mode")
+	}
+	if int(varID) < 0 || int(varID) >= len(wr.vars) {
+		return fmt.Errorf("gonc: unknown variable id %d", varID)
+	}
+	v := &wr.vars[varID]
+
+	ndims := len(v.dimIDs)
+	if len(start) != ndims || len(count) != ndims {
+		return fmt.Errorf("gonc: start/count length must match variable rank %d", ndims)
+	}
+
+	elemSize, err := ncTypeSize(v.dtype)
+	if err != nil {
+		return err
+	}
+
+	raw, err := encodeSlab(v.dtype, data)
+	if err != nil {
+		return err
+	}
+
+	total := 1
+	for i := 0; i < ndims; i++ {
+		if count[i] <= 0 {
+			return fmt.Errorf("gonc: count[%d] must be positive", i)
+		}
+		total *= count[i]
+	}
+	if len(raw) != total*elemSize {
+		return fmt.Errorf("gonc: data has %d elements, want %d", len(raw)/elemSize, total)
+	}
+
+	dimLens := make([]int, ndims)
+	for i, id := range v.dimIDs {
+		if i == 0 && v.isRecord {
+			continue
+		}
+		dimLens[i] = int(wr.dims[id].Length)
+		if start[i] < 0 || start[i]+count[i] > dimLens[i] {
+			return fmt.Errorf("gonc: hyperslab exceeds dim %d (length %d)", i, dimLens[i])
+		}
+	}
+
+	if v.isRecord {
+		if start[0] < 0 {
+			return fmt.Errorf("gonc: hyperslab exceeds dim 0")
+		}
+		if need := uint32(start[0] + count[0]); need > wr.numrecs {
+			wr.numrecs = need
+			if err := wr.updateNumRecs(); err != nil {
+				return err
+			}
+		}
+	}
+
+	recSize := wr.recSize()
+
+	elemStrides := make([]int64, ndims)
+	acc := int64(1)
+	for i := ndims - 1; i >= 0; i-- {
+		if v.isRecord && i == 0 {
+			continue
+		}
+		elemStrides[i] = acc
+		acc *= int64(dimLens[i])
+	}
+
+	idx := make([]int, ndims)
+	for n := 0; n < total; n++ {
+		off := int64(v.begin)
+		if v.isRecord {
+			rec := start[0] + idx[0]
+			off += int64(rec) * recSize
+			for d := 1; d < ndims; d++ {
+				off += int64(start[d]+idx[d]) * elemStrides[d] * int64(elemSize)
+			}
+		} else {
+			for d := 0; d < ndims; d++ {
+				off += int64(start[d]+idx[d]) * elemStrides[d] * int64(elemSize)
+			}
+		}
+
+		if _, err := wr.w.Seek(off, io.SeekStart); err != nil {
+			return err
+		}
+		if _, err := wr.w.Write(raw[n*elemSize : (n+1)*elemSize]); err != nil {
+			return err
+		}
+
+		for d := ndims - 1; d >= 0; d-- {
+			idx[d]++
+			if idx[d] < count[d] {
+				break
+			}
+			idx[d] = 0
+		}
+	}
+
+	return nil
+}
+
+func writeU32(w io.Writer, v uint32) error {
+	buf := make([]byte, 4)
+	binary.BigEndian.PutUint32(buf, v)
+	_, err := w.Write(buf)
+	return err
+}
+
+func writeU64(w io.Writer, v uint64) error {
+	buf := make([]byte, 8)
+	binary.BigEndian.PutUint64(buf, v)
+	_, err := w.Write(buf)
+	return err
+}
+
+func writeString(w io.Writer, s string) error {
+	if err := writeU32(w, uint32(len(s))); err != nil {
+		return err
+	}
+	if _, err := io.WriteString(w, s); err != nil {
+		return err
+	}
+	pad := (4 - (len(s) % 4)) % 4
+	if pad > 0 {
+		if _, err := w.Write(make([]byte, pad)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func writeDimList(w io.Writer, dims []Dimension) error {
+	if len(dims) == 0 {
+		return writeU32(w, 0)
+	}
+
+	if err := writeU32(w, 0x0A); err != nil {
+		return err
+	}
+	if err := writeU32(w, uint32(len(dims))); err != nil {
+		return err
+	}
+	for _, d := range dims {
+		if err := writeString(w, d.Name); err != nil {
+			return err
+		}
+		if err := writeU32(w, d.Length); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func writeAttrList(w io.Writer, attrs []Attribute) error {
+	if len(attrs) == 0 {
+		return writeU32(w, 0)
+	}
+
+	if err := writeU32(w, 0x0C); err != nil {
+		return err
+	}
+	if err := writeU32(w, uint32(len(attrs))); err != nil {
+		return err
+	}
+	for _, a := range attrs {
+		if err := writeString(w, a.Name); err != nil {
+			return err
+		}
+		if err := writeU32(w, a.Type); err != nil {
+			return err
+		}
+		if err := writeU32(w, uint32(len(a.Values))); err != nil {
+			return err
+		}
+		if _, err := w.Write(a.Values); err != nil {
+			return err
+		}
+		pad := (4 - (len(a.Values) % 4)) % 4
+		if pad > 0 {
+			if _, err := w.Write(make([]byte, pad)); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func writeVarList(w io.Writer, format byte, vars []writerVar) error {
+	if len(vars) == 0 {
+		return writeU32(w, 0)
+	}
+
+	if err := writeU32(w, 0x0B); err != nil {
+		return err
+	}
+	if err := writeU32(w, uint32(len(vars))); err != nil {
+		return err
+	}
+	for _, v := range vars {
+		if err := writeString(w, v.name); err != nil {
+			return err
+		}
+		if err := writeU32(w, uint32(len(v.dimIDs))); err != nil {
+			return err
+		}
+		for _, id := range v.dimIDs {
+			if err := writeU32(w, uint32(id)); err != nil {
+				return err
+			}
+		}
+		if err := writeAttrList(w, v.attrs); err != nil {
+			return err
+		}
+		if err := writeU32(w, v.dtype); err != nil {
+			return err
+		}
+		if err := writeU32(w, v.vsize); err != nil {
+			return err
+		}
+		if format == Format64BitOffset {
+			if err := writeU64(w, v.begin); err != nil {
+				return err
+			}
+		} else {
+			if err := writeU32(w, uint32(v.begin)); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// encodeAttrValues maps a Go value to an NC_* type and its big-endian
+// on-disk encoding, the inverse of decodeSlab.
+func encodeAttrValues(values interface{}) (uint32, []byte, error) {
+	switch v := values.(type) {
+	case string:
+		return NC_CHAR, []byte(v), nil
+	default:
+		raw, dtype, err := encodeTypedSlab(values)
+		if err != nil {
+			return 0, nil, err
+		}
+		return dtype, raw, nil
+	}
+}
+
+// encodeSlab converts data, a Go slice matching dtype, to its big-endian
+// on-disk encoding.
+func encodeSlab(dtype uint32, data interface{}) ([]byte, error) {
+	if dtype == NC_CHAR {
+		switch v := data.(type) {
+		case []byte:
+			return v, nil
+		case string:
+			return []byte(v), nil
+		}
+	}
+
+	raw, gotType, err := encodeTypedSlab(data)
+	if err != nil {
+		return nil, err
+	}
+	if gotType != dtype {
+		return nil, fmt.Errorf("gonc: data type does not match variable's NC_* type %d", dtype)
+	}
+	return raw, nil
+}
+
+// encodeTypedSlab encodes one of the numeric slice types to big-endian
+// bytes, reporting which NC_* type it matches.
+func encodeTypedSlab(data interface{}) ([]byte, uint32, error) {
+	switch v := data.(type) {
+	case []byte:
+		return v, NC_CHAR, nil
+	case []int8:
+		out := make([]byte, len(v))
+		for i, b := range v {
+			out[i] = byte(b)
+		}
+		return out, NC_BYTE, nil
+	case []int16:
+		out := make([]byte, len(v)*2)
+		for i, x := range v {
+			binary.BigEndian.PutUint16(out[i*2:], uint16(x))
+		}
+		return out, NC_SHORT, nil
+	case []int32:
+		out := make([]byte, len(v)*4)
+		for i, x := range v {
+			binary.BigEndian.PutUint32(out[i*4:], uint32(x))
+		}
+		return out, NC_INT, nil
+	case []float32:
+		out := make([]byte, len(v)*4)
+		for i, x := range v {
+			binary.BigEndian.PutUint32(out[i*4:], math.Float32bits(x))
+		}
+		return out, NC_FLOAT, nil
+	case []float64:
+		out := make([]byte, len(v)*8)
+		for i, x := range v {
+			binary.BigEndian.PutUint64(out[i*8:], math.Float64bits(x))
+		}
+		return out, NC_DOUBLE, nil
+	default:
+		return nil, 0, fmt.Errorf("gonc: unsupported attribute/data value type %T", data)
+	}
+}